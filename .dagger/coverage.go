@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"dagger/just-mcp/internal/dagger"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tarpaulinReport is the subset of tarpaulin's JSON report we care about
+type tarpaulinReport struct {
+	Covered   int     `json:"covered"`
+	Coverable int     `json:"coverable"`
+	Coverage  float64 `json:"coverage"`
+	Files     []struct {
+		// Absolute path split into components, e.g. ["/", "src", "src", "lib.rs"]
+		Path      []string `json:"path"`
+		Covered   int      `json:"covered"`
+		Coverable int      `json:"coverable"`
+	} `json:"files"`
+}
+
+// CoverageBadge computes coverage and emits a shields.io endpoint JSON file
+// suitable for a README badge
+func (m *JustMcp) CoverageBadge(ctx context.Context, source *dagger.Directory) (*dagger.File, error) {
+	percent, err := m.coveragePercent(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	badge, err := json.Marshal(map[string]any{
+		"schemaVersion": 1,
+		"label":         "coverage",
+		"message":       fmt.Sprintf("%.0f%%", percent),
+		"color":         coverageColor(percent),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.Directory().
+		WithNewFile("coverage-badge.json", string(badge)).
+		File("coverage-badge.json"), nil
+}
+
+// CoverageDir generates the tarpaulin HTML report and returns the whole output
+// directory, including any assets the report references, for publishing
+func (m *JustMcp) CoverageDir(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Tarpaulin image to use, e.g. a newer tag or an internal mirror
+	// +optional
+	// +default="xd009642/tarpaulin:0.31.2"
+	tarpaulinImage string,
+) (*dagger.Directory, error) {
+	return m.tarpaulin(source, tarpaulinImage, "", "Html").
+		Directory("/coverage"), nil
+}
+
+// CoveragePerFile fails when any source file's line coverage is below
+// minPercent, so a well-tested module can't mask an untested one. Exclusions
+// are path.Match patterns (e.g. "src/generated/*") or directory prefixes
+// ending in "/", relative to the crate
+func (m *JustMcp) CoveragePerFile(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Minimum line coverage percentage required for every file
+	// +optional
+	// +default=50
+	minPercent float64,
+	// Files to skip, e.g. generated code
+	// +optional
+	exclude []string,
+) (string, error) {
+	report, err := m.tarpaulinJSON(ctx, source)
+	if err != nil {
+		return "", err
+	}
+
+	var lines, failures []string
+	for _, file := range report.Files {
+		if file.Coverable == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(path.Join(file.Path...), m.workdir()+"/")
+		if coverageExcluded(name, exclude) {
+			continue
+		}
+
+		percent := float64(file.Covered) / float64(file.Coverable) * 100
+		line := fmt.Sprintf("%-60s %6.2f%% (%d/%d)", name, percent, file.Covered, file.Coverable)
+		lines = append(lines, line)
+		if percent < minPercent {
+			failures = append(failures, line)
+		}
+	}
+	sort.Strings(lines)
+	sort.Strings(failures)
+
+	if len(failures) > 0 {
+		return "", fmt.Errorf("%d file(s) below %.1f%% coverage:\n%s", len(failures), minPercent, strings.Join(failures, "\n"))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// coverageExcluded reports whether a crate-relative file matches any exclusion
+func coverageExcluded(name string, exclude []string) bool {
+	for _, pattern := range exclude {
+		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(name, pattern) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tarpaulinExcludes finds the exclude-files arrays in a tarpaulin config
+var tarpaulinExcludes = regexp.MustCompile(`(?s)exclude-files\s*=\s*\[(.*?)\]`)
+
+// tomlString matches a basic or literal TOML string
+var tomlString = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// tarpaulinGlob converts a tarpaulin exclude-files glob to a regexp; like
+// tarpaulin's glob matching, * also matches across path separators
+func tarpaulinGlob(pattern string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+// verifyTarpaulinExcludes checks that no file matching the config's
+// exclude-files made it into the JSON report, since tarpaulin silently
+// ignores patterns it can't apply
+func (m *JustMcp) verifyTarpaulinExcludes(ctx context.Context, source *dagger.Directory, config string, reportFile *dagger.File) error {
+	contents, err := source.File(path.Join(m.Subdir, config)).Contents(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read tarpaulin config %s: %w", config, err)
+	}
+	var patterns []string
+	for _, list := range tarpaulinExcludes.FindAllStringSubmatch(contents, -1) {
+		for _, value := range tomlString.FindAllStringSubmatch(list[1], -1) {
+			patterns = append(patterns, value[1]+value[2])
+		}
+	}
+	if len(patterns) == 0 {
+		fmt.Printf("⚠️  %s has no exclude-files, nothing to verify\n", config)
+		return nil
+	}
+
+	reportJSON, err := reportFile.Contents(ctx)
+	if err != nil {
+		return err
+	}
+	var report tarpaulinReport
+	if err := json.Unmarshal([]byte(reportJSON), &report); err != nil {
+		return fmt.Errorf("failed to parse tarpaulin report: %w", err)
+	}
+
+	var leaked []string
+	for _, file := range report.Files {
+		name := strings.TrimPrefix(path.Join(file.Path...), m.workdir()+"/")
+		for _, pattern := range patterns {
+			if tarpaulinGlob(pattern).MatchString(name) {
+				leaked = append(leaked, fmt.Sprintf("%s (excluded by %q)", name, pattern))
+				break
+			}
+		}
+	}
+	if len(leaked) > 0 {
+		sort.Strings(leaked)
+		return fmt.Errorf("excluded files appear in the coverage report:\n%s", strings.Join(leaked, "\n"))
+	}
+	fmt.Printf("✅ %d exclude pattern(s) respected\n", len(patterns))
+	return nil
+}
+
+// tarpaulinJSON runs tarpaulin and parses its JSON report
+func (m *JustMcp) tarpaulinJSON(ctx context.Context, source *dagger.Directory) (*tarpaulinReport, error) {
+	contents, err := m.tarpaulin(source, "", "", "Json").
+		File("/coverage/tarpaulin-report.json").
+		Contents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var report tarpaulinReport
+	if err := json.Unmarshal([]byte(contents), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse tarpaulin report: %w", err)
+	}
+	return &report, nil
+}
+
+// coveragePercent runs tarpaulin and returns the overall line coverage
+func (m *JustMcp) coveragePercent(ctx context.Context, source *dagger.Directory) (float64, error) {
+	report, err := m.tarpaulinJSON(ctx, source)
+	if err != nil {
+		return 0, err
+	}
+	if report.Coverable > 0 {
+		return float64(report.Covered) / float64(report.Coverable) * 100, nil
+	}
+	return report.Coverage, nil
+}
+
+// coverageColor picks a shields.io color for a coverage percentage
+func coverageColor(percent float64) string {
+	switch {
+	case percent >= 90:
+		return "brightgreen"
+	case percent >= 80:
+		return "green"
+	case percent >= 70:
+		return "yellowgreen"
+	case percent >= 60:
+		return "yellow"
+	case percent >= 50:
+		return "orange"
+	default:
+		return "red"
+	}
+}
+
+// CoverallsUpload generates an lcov report with tarpaulin and uploads it to
+// Coveralls with the official coverage reporter. The repo token is passed to
+// the reporter as a secret environment variable and never printed
+func (m *JustMcp) CoverallsUpload(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Coveralls repo token
+	token *dagger.Secret,
+	// CI job identifier Coveralls uses to group uploads, e.g. the run ID
+	serviceJobId string,
+	// CI service name reported to Coveralls
+	// +optional
+	// +default="github"
+	serviceName string,
+) (string, error) {
+	if serviceName == "" {
+		serviceName = "github"
+	}
+
+	fmt.Println("📊 Generating lcov coverage report...")
+	lcov := m.tarpaulin(source, "", "", "Lcov").File("/coverage/lcov.info")
+
+	fmt.Printf("📤 Uploading coverage to Coveralls (%s job %s)...\n", serviceName, serviceJobId)
+	out, err := dag.Container().
+		From(defaultArchiveImage).
+		WithExec([]string{"apk", "add", "--no-cache", "curl"}).
+		WithExec([]string{"sh", "-c", "curl -sSfL https://coveralls.io/coveralls-linux.tar.gz | tar -xz -C /usr/local/bin"}).
+		WithFile("/coverage/lcov.info", lcov).
+		// Coveralls expects paths relative to the repository root
+		WithExec([]string{"sed", "-i", "s|^SF:/src/|SF:|", "/coverage/lcov.info"}).
+		WithSecretVariable("COVERALLS_REPO_TOKEN", token).
+		WithEnvVariable("COVERALLS_SERVICE_NAME", serviceName).
+		WithEnvVariable("COVERALLS_SERVICE_JOB_ID", serviceJobId).
+		WithExec([]string{"coveralls", "report", "/coverage/lcov.info", "--format", "lcov"}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("coveralls upload failed: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,701 @@
+package main
+
+import (
+	"context"
+	"dagger/just-mcp/internal/dagger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fixtureJustfile is used when no fixtures are provided to the end-to-end checks
+const fixtureJustfile = `# Say hello
+hello name="world":
+    echo "hello {{name}}"
+
+# Run the tests
+test:
+    echo "testing"
+`
+
+// TestBinary runs integration-style checks against an already-built binary
+// without recompiling, for build-once-test-many pipelines. The fixtures
+// directory is used as the watch directory and should contain a justfile
+func (m *JustMcp) TestBinary(
+	ctx context.Context,
+	binary *dagger.File,
+	// +optional
+	fixtures *dagger.Directory,
+) (string, error) {
+	container := runtimeContainer(binary, fixtures)
+
+	if _, err := container.WithExec([]string{"just-mcp", "--version"}).Sync(ctx); err != nil {
+		return "", fmt.Errorf("just-mcp --version failed: %w", err)
+	}
+	if _, err := container.WithExec([]string{"just-mcp", "--help"}).Sync(ctx); err != nil {
+		return "", fmt.Errorf("just-mcp --help failed: %w", err)
+	}
+
+	out, err := container.
+		WithExec([]string{"just-mcp", "--watch-dir", "/fixtures"}, dagger.ContainerWithExecOpts{
+			Stdin: mcpListToolsSession,
+		}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("MCP session failed: %w", err)
+	}
+	if !strings.Contains(out, `"id":2`) || !strings.Contains(out, `"tools"`) {
+		return "", fmt.Errorf("tools/list returned no tools:\n%s", out)
+	}
+
+	return out, nil
+}
+
+// RuntimeCompatTest runs `just-mcp --version` in each runtime image to check
+// the binary starts on those distributions, e.g. that a glibc build doesn't
+// need a newer glibc than an older distro ships. All images are tried and the
+// failures reported together
+func (m *JustMcp) RuntimeCompatTest(
+	ctx context.Context,
+	binary *dagger.File,
+	// Images to test in
+	// +optional
+	// +default=["ubuntu:20.04","ubuntu:24.04","debian:11","debian:12","alpine:3.20"]
+	images []string,
+) (string, error) {
+	if len(images) == 0 {
+		images = []string{"ubuntu:20.04", "ubuntu:24.04", "debian:11", "debian:12", "alpine:3.20"}
+	}
+
+	var report, failures []string
+	for _, image := range images {
+		fmt.Printf("🧪 Running binary on %s...\n", image)
+		passed, out := execCapture(ctx,
+			dag.Container().
+				From(image).
+				WithFile("/usr/local/bin/just-mcp", binary, dagger.ContainerWithFileOpts{Permissions: 0o755}),
+			[]string{"just-mcp", "--version"})
+		if passed {
+			report = append(report, fmt.Sprintf("✅ %-20s %s", image, strings.TrimSpace(out)))
+		} else {
+			report = append(report, fmt.Sprintf("❌ %s", image))
+			failures = append(failures, fmt.Sprintf("%s:\n%s", image, strings.TrimSpace(out)))
+		}
+	}
+
+	if len(failures) > 0 {
+		return "", fmt.Errorf("%s\n\n%s", strings.Join(report, "\n"), strings.Join(failures, "\n\n"))
+	}
+	return strings.Join(report, "\n"), nil
+}
+
+// VerifyBinaryVersion checks the version reported by `just-mcp --version`
+// matches expected, ignoring a leading v (so a v1.2.3 tag matches 1.2.3)
+func (m *JustMcp) VerifyBinaryVersion(ctx context.Context, binary *dagger.File, expected string) (string, error) {
+	out, err := runtimeContainer(binary, nil).
+		WithExec([]string{"just-mcp", "--version"}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("just-mcp --version failed: %w", err)
+	}
+
+	// Output is "just-mcp <version>"
+	fields := strings.Fields(out)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("unexpected --version output: %q", out)
+	}
+	actual := fields[len(fields)-1]
+
+	if strings.TrimPrefix(actual, "v") != strings.TrimPrefix(expected, "v") {
+		return "", fmt.Errorf("binary reports version %s, expected %s", actual, expected)
+	}
+	return fmt.Sprintf("✅ Binary reports version %s", actual), nil
+}
+
+// qemuArch describes how to emulate a target architecture with qemu-user
+type qemuArch struct {
+	// qemu-user binary suffix
+	qemu string
+	// Debian architecture providing the cross glibc (libc6-<debian>-cross)
+	debian string
+	// Sysroot of the cross glibc under /usr
+	sysroot string
+}
+
+// qemuArches maps the architecture part of target triples to qemu-user
+var qemuArches = map[string]qemuArch{
+	"x86_64":    {"x86_64", "amd64", "x86_64-linux-gnu"},
+	"aarch64":   {"aarch64", "arm64", "aarch64-linux-gnu"},
+	"armv7":     {"arm", "armhf", "arm-linux-gnueabihf"},
+	"riscv64gc": {"riscv64", "riscv64", "riscv64-linux-gnu"},
+}
+
+// QemuSmokeTest runs a cross-compiled Linux binary's --version under
+// qemu-user emulation to confirm it actually executes. glibc binaries run
+// against Debian's cross glibc; static musl binaries need nothing extra
+func (m *JustMcp) QemuSmokeTest(
+	ctx context.Context,
+	binary *dagger.File,
+	// Rust target triple the binary was built for, e.g. aarch64-unknown-linux-musl
+	target string,
+) (string, error) {
+	if !strings.Contains(target, "-linux-") {
+		return "", fmt.Errorf("qemu smoke tests only support linux targets, got %s", target)
+	}
+	archName, _, _ := strings.Cut(target, "-")
+	arch, ok := qemuArches[archName]
+	if !ok {
+		return "", fmt.Errorf("no qemu emulation configured for %s", target)
+	}
+
+	packages := "qemu-user-static"
+	args := []string{"qemu-" + arch.qemu + "-static"}
+	if !strings.HasSuffix(target, "-musl") {
+		packages += " libc6-" + arch.debian + "-cross"
+		args = append(args, "-L", "/usr/"+arch.sysroot)
+	}
+	args = append(args, "/just-mcp", "--version")
+
+	// Invoking qemu directly avoids depending on the host's binfmt_misc setup
+	out, err := dag.Container().
+		From("debian:bookworm-slim").
+		WithExec([]string{"sh", "-c", "apt-get update && apt-get install -y " + packages}).
+		WithFile("/just-mcp", binary, dagger.ContainerWithFileOpts{Permissions: 0o755}).
+		WithExec(args).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s binary failed to run under qemu: %w", target, err)
+	}
+	return out, nil
+}
+
+// neededLibrary extracts the library name from a readelf NEEDED entry
+var neededLibrary = regexp.MustCompile(`\(NEEDED\).*\[(.+)\]`)
+
+// CheckDynamicDeps checks that the binary links exactly the expected shared
+// libraries, e.g. none at all for static musl builds. Dependencies are read
+// from the ELF dynamic section with readelf rather than ldd, which can't load
+// binaries built for another architecture
+func (m *JustMcp) CheckDynamicDeps(
+	ctx context.Context,
+	binary *dagger.File,
+	// Shared libraries the binary may link, e.g. ["libc.so.6"]; empty for static
+	// +optional
+	expected []string,
+) (string, error) {
+	container := dag.Container().
+		From("debian:bookworm-slim").
+		WithExec([]string{"sh", "-c", "apt-get update && apt-get install -y binutils file"}).
+		WithFile("/just-mcp", binary)
+
+	kind, err := container.WithExec([]string{"file", "-b", "/just-mcp"}).Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect binary: %w", err)
+	}
+	dynamic, err := container.WithExec([]string{"readelf", "-d", "/just-mcp"}).Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read dynamic section: %w", err)
+	}
+
+	var needed []string
+	for _, match := range neededLibrary.FindAllStringSubmatch(dynamic, -1) {
+		needed = append(needed, match[1])
+	}
+
+	var problems []string
+	for _, lib := range needed {
+		if !slices.Contains(expected, lib) {
+			problems = append(problems, "unexpected dependency: "+lib)
+		}
+	}
+	for _, lib := range expected {
+		if !slices.Contains(needed, lib) {
+			problems = append(problems, "missing dependency: "+lib)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return "", fmt.Errorf("dynamic dependencies don't match:\n%s\n\n%s", strings.Join(problems, "\n"), strings.TrimSpace(kind))
+	}
+
+	if len(needed) == 0 {
+		return "✅ Binary has no dynamic dependencies: " + strings.TrimSpace(kind), nil
+	}
+	return fmt.Sprintf("✅ Binary links only the expected libraries: %s", strings.Join(needed, ", ")), nil
+}
+
+// runtimeContainer returns a minimal container with the binary on PATH and the
+// fixtures (or a default justfile) at /fixtures
+func runtimeContainer(binary *dagger.File, fixtures *dagger.Directory) *dagger.Container {
+	if fixtures == nil {
+		fixtures = dag.Directory().WithNewFile("justfile", fixtureJustfile)
+	}
+
+	// Same Debian release as the rust image, so glibc builds run unchanged
+	return dag.Container().
+		From("debian:bookworm-slim").
+		WithFile("/usr/local/bin/just-mcp", binary, dagger.ContainerWithFileOpts{Permissions: 0o755}).
+		WithDirectory("/fixtures", fixtures).
+		WithWorkdir("/fixtures")
+}
+
+// ToolManifest returns the JSON manifest of tools the server exposes over MCP
+// for the fixtures (or a default justfile). When golden is given, the manifest
+// must match it exactly, catching accidental changes to the tool surface
+func (m *JustMcp) ToolManifest(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Committed manifest to compare against
+	// +optional
+	golden *dagger.File,
+	// +optional
+	fixtures *dagger.Directory,
+) (*dagger.File, error) {
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+
+	out, err := runtimeContainer(binary, fixtures).
+		WithExec([]string{"just-mcp", "--watch-dir", "/fixtures"}, dagger.ContainerWithExecOpts{
+			Stdin: mcpListToolsSession,
+		}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("MCP session failed: %w", err)
+	}
+
+	result, err := mcpResult(out, 2)
+	if err != nil {
+		return nil, err
+	}
+	var listing struct {
+		Tools []map[string]any `json:"tools"`
+	}
+	if err := json.Unmarshal(result, &listing); err != nil {
+		return nil, fmt.Errorf("failed to parse tools/list result: %w", err)
+	}
+	sort.Slice(listing.Tools, func(i, j int) bool {
+		return fmt.Sprint(listing.Tools[i]["name"]) < fmt.Sprint(listing.Tools[j]["name"])
+	})
+
+	manifest, err := json.MarshalIndent(listing, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	contents := string(manifest) + "\n"
+
+	if golden != nil {
+		expected, err := golden.Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read golden manifest: %w", err)
+		}
+		if strings.TrimSpace(expected) != strings.TrimSpace(contents) {
+			return nil, fmt.Errorf("tool manifest differs from golden file:\n%s", contents)
+		}
+	}
+
+	return dag.Directory().
+		WithNewFile("tools.json", contents).
+		File("tools.json"), nil
+}
+
+// mcpResult finds the JSON-RPC response with the given id in a stdio session
+// transcript and returns its result, failing on error responses
+func mcpResult(transcript string, id int) (json.RawMessage, error) {
+	for _, line := range strings.Split(transcript, "\n") {
+		var resp struct {
+			ID     *int            `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  json.RawMessage `json:"error"`
+		}
+		if json.Unmarshal([]byte(line), &resp) != nil || resp.ID == nil || *resp.ID != id {
+			continue
+		}
+		if resp.Error != nil {
+			return nil, &mcpError{id: id, body: resp.Error}
+		}
+		return resp.Result, nil
+	}
+	return nil, fmt.Errorf("no response for request %d in session output:\n%s", id, transcript)
+}
+
+// mcpError is a JSON-RPC error response to a request in a session transcript
+type mcpError struct {
+	id   int
+	body json.RawMessage
+}
+
+func (e *mcpError) Error() string {
+	return fmt.Sprintf("request %d failed: %s", e.id, e.body)
+}
+
+// ServerSession replays a scripted MCP client session against the built server
+// The script holds one JSON-RPC message per line and is fed to the server over
+// stdio; the server's stdout is returned for comparison against a recording
+func (m *JustMcp) ServerSession(
+	ctx context.Context,
+	source *dagger.Directory,
+	script *dagger.File,
+	// Directory to watch, should contain a justfile (defaults to a sample one)
+	// +optional
+	fixtures *dagger.Directory,
+) (string, error) {
+	input, err := script.Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read session script: %w", err)
+	}
+
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+
+	return runtimeContainer(binary, fixtures).
+		WithExec([]string{"just-mcp", "--watch-dir", "/fixtures"}, dagger.ContainerWithExecOpts{
+			Stdin: input,
+		}).
+		Stdout(ctx)
+}
+
+// sentinelJustfile has a recipe that leaves a file behind when it runs
+const sentinelJustfile = `# Write the sentinel file
+sentinel:
+    echo "ran" > /tmp/just-mcp-sentinel
+`
+
+// recipeCallSession lists the tools and then calls the sentinel recipe
+const recipeCallSession = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"dagger","version":"0.0.0"}}}
+{"jsonrpc":"2.0","method":"notifications/initialized"}
+{"jsonrpc":"2.0","id":2,"method":"tools/list","params":{}}
+{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"sentinel","arguments":{}}}
+`
+
+// RecipeExecutionTest drives the server through tools/call to run a recipe from
+// a fixture justfile, then checks the file the recipe writes exists
+func (m *JustMcp) RecipeExecutionTest(ctx context.Context, source *dagger.Directory) (string, error) {
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+	if err := recipeExecution(ctx, binary, justBinary(m.JustVersion, "linux/amd64"), false); err != nil {
+		return "", err
+	}
+	return "✅ Recipe executed through tools/call", nil
+}
+
+// OfflineBehaviorTest runs the recipe execution check with no network access
+// (not even DNS), so the server must start, list tools and run a network-free
+// recipe without reaching any external service
+func (m *JustMcp) OfflineBehaviorTest(ctx context.Context, source *dagger.Directory) (string, error) {
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+	if err := recipeExecution(ctx, binary, justBinary(m.JustVersion, "linux/amd64"), true); err != nil {
+		return "", fmt.Errorf("offline: %w", err)
+	}
+	return "✅ Server started, listed tools and ran a recipe without network access", nil
+}
+
+// recipeExecution runs the sentinel recipe through the server with the given
+// just binary and checks it wrote its file. When offline, the server runs in
+// its own network namespace with only a loopback interface
+func recipeExecution(ctx context.Context, binary *dagger.File, just *dagger.File, offline bool) error {
+	fixtures := dag.Directory().WithNewFile("justfile", sentinelJustfile)
+
+	args := []string{"just-mcp", "--watch-dir", "/fixtures"}
+	if offline {
+		// Creating a network namespace needs CAP_SYS_ADMIN
+		args = append([]string{"unshare", "--net"}, args...)
+	}
+	container := runtimeContainer(binary, fixtures).
+		WithFile("/usr/local/bin/just", just, dagger.ContainerWithFileOpts{Permissions: 0o755}).
+		WithExec(args, dagger.ContainerWithExecOpts{
+			Stdin:                    recipeCallSession,
+			InsecureRootCapabilities: offline,
+		})
+
+	out, err := container.Stdout(ctx)
+	if err != nil {
+		return fmt.Errorf("MCP session failed: %w", err)
+	}
+
+	tools, err := mcpResult(out, 2)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(tools), `"sentinel"`) {
+		return fmt.Errorf("tools/list didn't include the sentinel recipe: %s", tools)
+	}
+
+	result, err := mcpResult(out, 3)
+	if err != nil {
+		return err
+	}
+	var call struct {
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(result, &call); err != nil {
+		return fmt.Errorf("failed to parse tools/call result: %w", err)
+	}
+	if call.IsError {
+		return fmt.Errorf("sentinel recipe failed: %s", result)
+	}
+
+	sentinel, err := container.File("/tmp/just-mcp-sentinel").Contents(ctx)
+	if err != nil {
+		return fmt.Errorf("sentinel recipe did not write its file: %w", err)
+	}
+	if strings.TrimSpace(sentinel) != "ran" {
+		return fmt.Errorf("unexpected sentinel contents: %q", sentinel)
+	}
+
+	return nil
+}
+
+// JustVersionMatrix checks the server against several just releases: for each
+// version the fixture justfile must parse with that just and the server must
+// list its recipes and run one through tools/call. All versions are tried and
+// the failures reported together
+func (m *JustMcp) JustVersionMatrix(
+	ctx context.Context,
+	source *dagger.Directory,
+	// just release tags to test, e.g. ["1.25.0","1.36.0"]
+	versions []string,
+) (string, error) {
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no just versions given")
+	}
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+
+	var report, failures []string
+	for _, version := range versions {
+		fmt.Printf("🧪 Testing against just %s...\n", version)
+		just := justBinary(version, "linux/amd64")
+
+		_, err := runtimeContainer(binary, nil).
+			WithFile("/usr/local/bin/just", just, dagger.ContainerWithFileOpts{Permissions: 0o755}).
+			WithExec([]string{"just", "--summary"}).
+			Sync(ctx)
+		if err != nil {
+			err = fmt.Errorf("fixture justfile failed to parse: %w", err)
+		} else {
+			err = recipeExecution(ctx, binary, just, false)
+		}
+
+		if err != nil {
+			report = append(report, fmt.Sprintf("❌ just %s", version))
+			failures = append(failures, fmt.Sprintf("just %s: %v", version, err))
+		} else {
+			report = append(report, fmt.Sprintf("✅ just %s", version))
+		}
+	}
+
+	if len(failures) > 0 {
+		return "", fmt.Errorf("%s\n\n%s", strings.Join(report, "\n"), strings.Join(failures, "\n"))
+	}
+	return strings.Join(report, "\n"), nil
+}
+
+// malformedJustfile is deliberately broken: an unterminated string, a recipe
+// body without a recipe and a dependency on a recipe that doesn't exist
+const malformedJustfile = `# Broken recipe
+broken name="unterminated:
+    echo "{{name}}"
+
+	orphaned body line
+
+build: missing-dependency
+    echo "build"
+`
+
+// malformedSession lists the tools and then calls a recipe from the broken
+// justfile, which must fail with a structured error
+const malformedSession = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"dagger","version":"0.0.0"}}}
+{"jsonrpc":"2.0","method":"notifications/initialized"}
+{"jsonrpc":"2.0","id":2,"method":"tools/list","params":{}}
+{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"broken","arguments":{}}}
+`
+
+// MalformedJustfileTest feeds a broken justfile to the server and checks it
+// keeps serving requests, answers a call into the broken justfile with a
+// structured error, exits cleanly and never panics
+func (m *JustMcp) MalformedJustfileTest(ctx context.Context, source *dagger.Directory) (string, error) {
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+	fixtures := dag.Directory().WithNewFile("justfile", malformedJustfile)
+
+	session := runtimeContainer(binary, fixtures).
+		WithExec([]string{"just-mcp", "--watch-dir", "/fixtures"}, dagger.ContainerWithExecOpts{
+			Stdin:  malformedSession,
+			Expect: dagger.ReturnTypeAny,
+		})
+
+	exitCode, err := session.ExitCode(ctx)
+	if err != nil {
+		return "", err
+	}
+	stdout, err := session.Stdout(ctx)
+	if err != nil {
+		return "", err
+	}
+	stderr, err := session.Stderr(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.Contains(stderr, "panicked at") {
+		return "", fmt.Errorf("server panicked on a malformed justfile:\n%s", stderr)
+	}
+	if exitCode != 0 {
+		return "", fmt.Errorf("server exited with code %d on a malformed justfile:\n%s", exitCode, stderr)
+	}
+	if _, err := mcpResult(stdout, 2); err != nil {
+		return "", fmt.Errorf("server stopped answering after a malformed justfile: %w", err)
+	}
+
+	// Either a JSON-RPC error or a tool result flagged isError is acceptable
+	result, err := mcpResult(stdout, 3)
+	if err == nil {
+		var call struct {
+			IsError bool `json:"isError"`
+		}
+		if json.Unmarshal(result, &call) != nil || !call.IsError {
+			return "", fmt.Errorf("calling a recipe from a malformed justfile succeeded: %s", result)
+		}
+	} else if !errors.As(err, new(*mcpError)) {
+		return "", err
+	}
+
+	return "✅ Server handled the malformed justfile gracefully", nil
+}
+
+// signalTestScript starts the server on a FIFO so stdin stays open, waits for
+// the initialize response, sends SIGTERM and waits up to $TIMEOUT seconds for
+// it to exit. The server's stderr is printed as the shutdown log and the
+// script exits with the server's status, or 124 if it had to be killed
+const signalTestScript = `mkfifo /tmp/in /tmp/out
+just-mcp --watch-dir /fixtures < /tmp/in > /tmp/out 2>/tmp/server.log &
+pid=$!
+exec 3>/tmp/in 4</tmp/out
+
+cat /tmp/initialize.jsonl >&3
+while read -r line <&4; do case "$line" in *'"id":1,'*|*'"id":1}'*) break ;; esac; done
+cat /dev/fd/4 > /dev/null &
+
+kill -TERM $pid
+for _ in $(seq $((TIMEOUT * 10))); do
+  kill -0 $pid 2>/dev/null || break
+  sleep 0.1
+done
+if kill -0 $pid 2>/dev/null; then
+  kill -KILL $pid
+  cat /tmp/server.log
+  exit 124
+fi
+wait $pid
+code=$?
+cat /tmp/server.log
+exit $code
+`
+
+// SignalTest starts the release server, completes the initialize handshake,
+// sends SIGTERM and checks it exits with status 0 within the timeout
+// The server's stderr is returned as the shutdown log
+func (m *JustMcp) SignalTest(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Seconds to wait for the server to exit after SIGTERM
+	// +optional
+	// +default=5
+	timeout int,
+) (string, error) {
+	if timeout <= 0 {
+		timeout = 5
+	}
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+
+	initialize, _, _ := strings.Cut(mcpListToolsSession, "\n")
+	session := runtimeContainer(binary, nil).
+		WithNewFile("/tmp/initialize.jsonl", initialize+"\n").
+		WithEnvVariable("TIMEOUT", strconv.Itoa(timeout)).
+		WithExec([]string{"bash", "-c", signalTestScript}, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		})
+
+	exitCode, err := session.ExitCode(ctx)
+	if err != nil {
+		return "", err
+	}
+	log, err := session.Stdout(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	switch exitCode {
+	case 0:
+		return "✅ Server shut down cleanly on SIGTERM\n" + log, nil
+	case 124:
+		return "", fmt.Errorf("server still running %ds after SIGTERM:\n%s", timeout, log)
+	case 128 + 15:
+		return "", fmt.Errorf("server was killed by SIGTERM instead of shutting down:\n%s", log)
+	}
+	return "", fmt.Errorf("server exited with code %d on SIGTERM:\n%s", exitCode, log)
+}
+
+// configReadSession reads the virtual config.json resource the server exposes
+const configReadSession = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"dagger","version":"0.0.0"}}}
+{"jsonrpc":"2.0","method":"notifications/initialized"}
+{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"file:///config.json"}}
+`
+
+// ConfigSchema validates the live file:///config.json resource, read over MCP,
+// against the committed config JSON schema, failing on drift, and returns the
+// validated schema. The binary has no schema generator, so nothing is
+// generated: the hand-maintained committed schema is the source of truth
+func (m *JustMcp) ConfigSchema(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Path of the committed schema within source
+	// +optional
+	// +default="docs/config-schema.json"
+	schemaPath string,
+) (*dagger.File, error) {
+	if schemaPath == "" {
+		schemaPath = "docs/config-schema.json"
+	}
+	schema := source.File(path.Join(m.Subdir, schemaPath))
+	if _, err := schema.Sync(ctx); err != nil {
+		return nil, fmt.Errorf("%s not found in source: %w", schemaPath, err)
+	}
+
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+	out, err := runtimeContainer(binary, nil).
+		WithExec([]string{"just-mcp", "--watch-dir", "/fixtures"}, dagger.ContainerWithExecOpts{
+			Stdin: configReadSession,
+		}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("MCP session failed: %w", err)
+	}
+
+	result, err := mcpResult(out, 2)
+	if err != nil {
+		return nil, err
+	}
+	var resource struct {
+		Contents []struct {
+			Text string `json:"text"`
+		} `json:"contents"`
+	}
+	if err := json.Unmarshal(result, &resource); err != nil || len(resource.Contents) == 0 {
+		return nil, fmt.Errorf("unexpected resources/read result: %s", result)
+	}
+
+	fmt.Printf("🔍 Validating config.json against %s...\n", schemaPath)
+	validator := dag.Container().
+		From("python:3.12-alpine").
+		WithExec([]string{"pip", "install", "--no-cache-dir", "check-jsonschema"}).
+		WithFile("/schema.json", schema).
+		WithNewFile("/config.json", resource.Contents[0].Text)
+	if passed, out := execCapture(ctx, validator, []string{"check-jsonschema", "--schemafile", "/schema.json", "/config.json"}); !passed {
+		return nil, fmt.Errorf("config.json no longer matches %s:\n%s", schemaPath, out)
+	}
+
+	fmt.Printf("✅ config.json matches %s\n", schemaPath)
+	return schema, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"dagger/just-mcp/internal/dagger"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DockerBuild builds the repository's Dockerfile and returns the image
+// Fails if the source has no Dockerfile or the build errors
+func (m *JustMcp) DockerBuild(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Path of the Dockerfile within source
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+	// Run `just-mcp --version` inside the built image as a smoke test
+	// +optional
+	smoke bool,
+) (*dagger.Container, error) {
+	image, err := dockerImage(ctx, source, dockerfile, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if smoke {
+		if _, err := image.WithExec([]string{"just-mcp", "--version"}).Sync(ctx); err != nil {
+			return nil, fmt.Errorf("smoke test failed: %w", err)
+		}
+	}
+
+	return image, nil
+}
+
+// dockerImage builds the Dockerfile for the platform, or the engine's default
+// platform when empty
+func dockerImage(ctx context.Context, source *dagger.Directory, dockerfile string, platform dagger.Platform) (*dagger.Container, error) {
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	entries, err := source.Entries(ctx, dagger.DirectoryEntriesOpts{Path: path.Dir(dockerfile)})
+	if err != nil || !slices.Contains(entries, path.Base(dockerfile)) {
+		return nil, fmt.Errorf("%s not found in source", dockerfile)
+	}
+
+	image, err := source.DockerBuild(dagger.DirectoryDockerBuildOpts{Dockerfile: dockerfile, Platform: platform}).Sync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("docker build failed: %w", err)
+	}
+	return image, nil
+}
+
+// ImageTarball builds the image for each platform and exports it as an OCI
+// layout tarball for loading into registries without pulling, e.g. on
+// airgapped hosts. Several platforms produce a multi-arch OCI index
+func (m *JustMcp) ImageTarball(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Path of the Dockerfile within source
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+	// Platforms to include in the image index
+	// +optional
+	// +default=["linux/amd64"]
+	platforms []string,
+) (*dagger.File, error) {
+	if len(platforms) == 0 {
+		platforms = []string{"linux/amd64"}
+	}
+
+	var variants []*dagger.Container
+	for _, platform := range platforms {
+		fmt.Printf("📦 Building image for %s...\n", platform)
+		image, err := dockerImage(ctx, source, dockerfile, dagger.Platform(platform))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", platform, err)
+		}
+		variants = append(variants, image)
+	}
+
+	return dag.Container().
+		AsTarball(dagger.ContainerAsTarballOpts{PlatformVariants: variants}).
+		WithName("just-mcp-image.tar"), nil
+}
+
+// ImageReport builds the Dockerfile the same way as DockerBuild and reports its
+// size and layer count, failing if it is larger than maxSizeMB
+func (m *JustMcp) ImageReport(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Path of the Dockerfile within source
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+	// Maximum allowed size of the exported image in megabytes
+	// +optional
+	// +default=200
+	maxSizeMB int,
+) (string, error) {
+	image, err := dockerImage(ctx, source, dockerfile, "")
+	if err != nil {
+		return "", err
+	}
+	tarball := image.AsTarball()
+
+	size, err := tarball.Size(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to export image: %w", err)
+	}
+
+	// The tarball is an OCI layout: index.json -> manifest -> layers
+	layers, err := dag.Container().
+		From(defaultArchiveImage).
+		WithExec([]string{"apk", "add", "--no-cache", "jq"}).
+		WithFile("/image.tar", tarball).
+		WithExec([]string{"mkdir", "-p", "/oci"}).
+		WithExec([]string{"tar", "-xf", "/image.tar", "-C", "/oci"}).
+		WithExec([]string{"sh", "-c",
+			`manifest=$(jq -r '.manifests[0].digest' /oci/index.json | cut -d: -f2) && jq '.layers | length' /oci/blobs/sha256/$manifest`}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image layers: %w", err)
+	}
+
+	sizeMB := float64(size) / (1024 * 1024)
+	report := fmt.Sprintf("📦 Image size: %.1f MB, layers: %s", sizeMB, strings.TrimSpace(layers))
+	if maxSizeMB > 0 && sizeMB > float64(maxSizeMB) {
+		return "", fmt.Errorf("image exceeds %d MB limit: %s", maxSizeMB, report)
+	}
+	return report, nil
+}
+
+// ScratchImage packages a static musl build of just-mcp, built with zig, into
+// an empty scratch image with the binary as entrypoint and CA certificates for
+// outbound TLS. The image has no shell or just, so it suits serving tool
+// listings and custom runtimes layered on top rather than running recipes
+func (m *JustMcp) ScratchImage(
+	ctx context.Context,
+	source *dagger.Directory,
+	// +optional
+	// +default="linux/amd64"
+	platform string,
+) (*dagger.Container, error) {
+	if err := validateLibc(platform, "musl"); err != nil {
+		return nil, err
+	}
+	binary := m.buildBinary(source, platform, buildOptions{release: true, libc: "musl"})
+
+	certs := dag.Container().
+		From(defaultArchiveImage).
+		WithExec([]string{"apk", "add", "--no-cache", "ca-certificates"}).
+		File("/etc/ssl/certs/ca-certificates.crt")
+
+	return dag.Container(dagger.ContainerOpts{Platform: dagger.Platform(platform)}).
+		WithFile("/etc/ssl/certs/ca-certificates.crt", certs).
+		WithFile("/just-mcp", binary, dagger.ContainerWithFileOpts{Permissions: 0o755}).
+		WithEntrypoint([]string{"/just-mcp"}), nil
+}
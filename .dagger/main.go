@@ -9,80 +9,581 @@ package main
 import (
 	"context"
 	"dagger/just-mcp/internal/dagger"
+	"encoding/json"
 	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 )
 
-type JustMcp struct{}
+type JustMcp struct {
+	// Subdirectory of the source containing the crate
+	// +private
+	Subdir string
+	// just release installed for tests; empty for the latest
+	// +private
+	JustVersion string
+}
+
+// New configures the pipeline; set subdir when the crate isn't at the source root
+func New(
+	// Subdirectory of the source containing the crate, for monorepo layouts
+	// +optional
+	subdir string,
+	// just release tag to install for tests, e.g. "1.36.0"; defaults to the latest
+	// +optional
+	justVersion string,
+) *JustMcp {
+	return &JustMcp{Subdir: subdir, JustVersion: justVersion}
+}
+
+// workdir returns the crate directory inside build containers
+func (m *JustMcp) workdir() string {
+	return path.Join("/src", m.Subdir)
+}
 
-// rustContainer creates a base Rust container with common tools
+// rustContainer creates a base Rust container with common tools, including
+// just for the tests that shell out to it
 func (m *JustMcp) rustContainer(source *dagger.Directory) *dagger.Container {
+	return m.buildContainer(source).
+		// Install just for tests
+		With(m.withJust("linux/amd64"))
+}
+
+// buildContainer creates a lean Rust container for stages that only compile
+// or check the code, skipping the just install
+func (m *JustMcp) buildContainer(source *dagger.Directory) *dagger.Container {
 	return dag.Container().
 		From("rust:1.88.0").
 		WithDirectory("/src", source).
-		WithWorkdir("/src").
+		WithWorkdir(m.workdir()).
 		WithExec([]string{"rustup", "component", "add", "rustfmt", "clippy"}).
-		// Install just for tests
-		WithExec([]string{"sh", "-c", "curl -qsSf https://just.systems/install.sh | bash -s -- --to /usr/local/bin"})
+		With(withCargoRegistryCache)
 }
 
-// Format checks Rust code formatting
-func (m *JustMcp) Format(ctx context.Context, source *dagger.Directory) (string, error) {
+// nightlyContainer extends rustContainer with a nightly toolchain for tools
+// that need unstable compiler features; invoke them via `cargo +nightly`
+func (m *JustMcp) nightlyContainer(source *dagger.Directory) *dagger.Container {
 	return m.rustContainer(source).
-		WithExec([]string{"cargo", "fmt", "--", "--check"}).
+		WithExec([]string{"rustup", "toolchain", "install", "nightly", "--profile", "minimal"})
+}
+
+// WarmCache pre-downloads all crate dependencies into the shared registry cache
+// Cargo has no stable way to build only dependencies, so this stops at fetching
+func (m *JustMcp) WarmCache(ctx context.Context, source *dagger.Directory) (string, error) {
+	return m.warmCacheExec(source).Stdout(ctx)
+}
+
+// warmCacheExec returns the container that fetches the dependencies
+func (m *JustMcp) warmCacheExec(source *dagger.Directory) *dagger.Container {
+	return m.buildContainer(source).
+		WithExec([]string{"cargo", "fetch"})
+}
+
+// ToolchainInfo reports the exact rustc and cargo versions builds run with
+func (m *JustMcp) ToolchainInfo(ctx context.Context, source *dagger.Directory) (string, error) {
+	return m.buildContainer(source).
+		WithExec([]string{"sh", "-c", "rustc --version --verbose && cargo --version"}).
+		Stdout(ctx)
+}
+
+// pipelineCargoTools are the cargo subcommands installed by pipeline stages
+var pipelineCargoTools = []string{
+	"cargo-nextest",
+	"cargo-audit",
+	"cargo-udeps",
+	"cargo-machete",
+	"cargo-spellcheck",
+	"cargo-hack",
+	"cargo-public-api",
+	"cargo-cyclonedx",
+	"cargo-license",
+}
+
+// ToolVersions installs the auxiliary tools the pipeline uses the same way the
+// stages do and reports their resolved versions as a JSON object of tool name
+// to version, so floating installs can be audited and pinned
+func (m *JustMcp) ToolVersions(ctx context.Context) (string, error) {
+	container := dag.Container().
+		From("rust:1.88.0").
+		With(withCargoRegistryCache).
+		// cargo-spellcheck's hunspell bindings are generated with bindgen
+		WithExec([]string{"sh", "-c", "apt-get update && apt-get install -y libclang-dev"})
+	for _, tool := range pipelineCargoTools {
+		fmt.Printf("📥 Installing %s...\n", tool)
+		container = container.WithExec([]string{"cargo", "install", tool, "--locked"})
+	}
+
+	// Lines look like "cargo-audit v0.21.0:" followed by indented binaries
+	installed, err := container.WithExec([]string{"cargo", "install", "--list"}).Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to install pipeline tools: %w", err)
+	}
+	versions := map[string]string{}
+	for _, line := range strings.Split(installed, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(line, " ") {
+			continue
+		}
+		versions[fields[0]] = strings.TrimPrefix(strings.TrimSuffix(fields[1], ":"), "v")
+	}
+
+	rustc, err := container.WithExec([]string{"rustc", "--version"}).Stdout(ctx)
+	if err != nil {
+		return "", err
+	}
+	versions["rustc"] = strings.Fields(rustc)[1]
+
+	just, err := dag.Container().
+		From(defaultArchiveImage).
+		With(m.withJust("linux/amd64")).
+		WithExec([]string{"just", "--version"}).
 		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to install just: %w", err)
+	}
+	versions["just"] = strings.TrimPrefix(strings.TrimSpace(just), "just ")
+
+	// The tarpaulin image is pinned by tag rather than installed
+	_, versions["cargo-tarpaulin"], _ = strings.Cut(defaultTarpaulinImage, ":")
+
+	report, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(report), nil
+}
+
+// Format checks Rust code formatting
+func (m *JustMcp) Format(ctx context.Context, source *dagger.Directory) (string, error) {
+	return m.formatExec(source).Stdout(ctx)
+}
+
+// formatExec returns the container that runs the format check
+func (m *JustMcp) formatExec(source *dagger.Directory) *dagger.Container {
+	return m.buildContainer(source).
+		WithExec([]string{"cargo", "fmt", "--", "--check"})
 }
 
 // Lint runs clippy on the Rust code
-func (m *JustMcp) Lint(ctx context.Context, source *dagger.Directory) (string, error) {
-	return m.rustContainer(source).
-		WithExec([]string{"cargo", "clippy", "--", "-D", "warnings"}).
+func (m *JustMcp) Lint(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Also fail on warnings from workspace members and path dependencies
+	// built along the way. Registry dependencies are compiled with
+	// --cap-lints allow, so their warnings are never reported
+	// +optional
+	denyDepWarnings bool,
+	// Cargo target directory relative to the crate; defaults to target/clippy
+	// (target/clippy-strict with denyDepWarnings)
+	// +optional
+	targetDir string,
+) (string, error) {
+	return m.lintExec(source, denyDepWarnings, targetDir).Stdout(ctx)
+}
+
+// lintExec returns the container that runs clippy for Lint
+func (m *JustMcp) lintExec(source *dagger.Directory, denyDepWarnings bool, targetDir string) *dagger.Container {
+	// Clippy gets its own target dir and cache volume so its artifacts don't
+	// evict the ones produced by regular builds
+	stage := "clippy"
+	if denyDepWarnings {
+		// RUSTFLAGS changes invalidate every artifact, so strict runs get a
+		// separate cache as well
+		stage = "clippy-strict"
+	}
+	if targetDir == "" {
+		targetDir = "target/" + stage
+	}
+
+	container := withCargoCache(m.buildContainer(source), stage, path.Join(m.workdir(), targetDir))
+	if denyDepWarnings {
+		// Applies to every crate rustc compiles, but cargo caps lints for
+		// registry crates, so only local (path) crates can fail here
+		container = container.WithEnvVariable("RUSTFLAGS", "-D warnings")
+	}
+
+	return container.
+		WithExec([]string{"cargo", "clippy", "--", "-D", "warnings"})
+}
+
+// LintJson runs clippy and returns its raw --message-format=json stream, one
+// cargo message per line, for tools that aggregate findings themselves
+// Compile errors are returned as error-level messages in the stream too
+func (m *JustMcp) LintJson(ctx context.Context, source *dagger.Directory) (string, error) {
+	return withCargoCache(m.buildContainer(source), "clippy", m.workdir()+"/target/clippy").
+		WithExec([]string{"cargo", "clippy", "--message-format=json"}, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		}).
 		Stdout(ctx)
 }
 
 // Test runs all tests for a specific platform
+// Non-native Linux platforms (e.g. linux/arm64 on an amd64 host) execute under
+// QEMU emulation, which works but is considerably slower than a native runner
 func (m *JustMcp) Test(
 	ctx context.Context,
 	source *dagger.Directory,
 	// +optional
 	// +default="linux/amd64"
 	platform string,
+	// Re-run failing tests up to this many times before declaring failure
+	// +optional
+	retries int,
+	// Cargo target directory relative to the crate, so tests don't contend
+	// for the build lock with stages running in parallel
+	// +optional
+	// +default="target/test"
+	targetDir string,
+	// Enable core dumps and append gdb backtraces of crashed test binaries to
+	// the failure. Needs the host's core_pattern to write plain core files
+	// +optional
+	captureCore bool,
+	// Directory of locally patched crates, one subdirectory per crate named
+	// after its package, used as [patch.crates-io] overrides
+	// +optional
+	patches *dagger.Directory,
+	// Report results in TAP (Test Anything Protocol) format
+	// +optional
+	tap bool,
+	// Number of parallel cargo jobs; 0 lets cargo decide
+	// +optional
+	jobs int,
 ) (string, error) {
-	container := dag.Container(dagger.ContainerOpts{Platform: dagger.Platform(platform)}).
+	if captureCore && retries > 0 {
+		return "", fmt.Errorf("captureCore can't be combined with retries")
+	}
+	if tap && (captureCore || retries > 0) {
+		return "", fmt.Errorf("tap can't be combined with captureCore or retries")
+	}
+
+	container, err := m.testSetup(ctx, source, platform, targetDir, patches, jobs)
+	if err != nil {
+		return "", err
+	}
+
+	if retries > 0 {
+		return runTestsWithRetries(ctx, container, retries)
+	}
+	if captureCore {
+		return runTestsWithCoreCapture(ctx, container)
+	}
+	if tap {
+		passed, out := execCapture(ctx, container, []string{"cargo", "test"})
+		report := libtestToTAP(out)
+		if !passed {
+			return "", fmt.Errorf("tests failed:\n%s", report)
+		}
+		return report, nil
+	}
+
+	return container.
+		WithExec([]string{"cargo", "test"}).
+		Stdout(ctx)
+}
+
+// testSetup prepares the test container for Test: target dir, crate patches
+// and the cargo job limit
+func (m *JustMcp) testSetup(ctx context.Context, source *dagger.Directory, platform string, targetDir string, patches *dagger.Directory, jobs int) (*dagger.Container, error) {
+	// Only Linux containers can execute the test binaries
+	if !strings.HasPrefix(platform, "linux/") {
+		return nil, fmt.Errorf("tests can only run on linux platforms, got %s", platform)
+	}
+
+	container := m.testContainer(source, platform)
+	if targetDir != "" {
+		container = container.WithEnvVariable("CARGO_TARGET_DIR", path.Join(m.workdir(), targetDir))
+	}
+	if patches != nil {
+		config, err := cargoPatchConfig(ctx, patches)
+		if err != nil {
+			return nil, err
+		}
+		container = container.With(withCargoPatches(patches, config))
+	}
+	if jobs > 0 {
+		// Equivalent to -j, but also covers nextest and the core capture script
+		container = container.WithEnvVariable("CARGO_BUILD_JOBS", strconv.Itoa(jobs))
+	}
+	return container, nil
+}
+
+// TestTargetFeatures runs the tests with extra target features enabled
+// (RUSTFLAGS="-C target-feature=<features>", e.g. "+avx2,+fma") to exercise
+// CPU-feature gated code paths. The host CPU must support those features,
+// otherwise the test binaries crash with illegal instructions
+func (m *JustMcp) TestTargetFeatures(
+	ctx context.Context,
+	source *dagger.Directory,
+	features string,
+) (string, error) {
+	return m.testContainer(source, "linux/amd64").
+		WithEnvVariable("RUSTFLAGS", "-C target-feature="+features).
+		WithExec([]string{"cargo", "test"}).
+		Stdout(ctx)
+}
+
+// TestThreadSanitizer runs the tests under ThreadSanitizer to catch data races
+// in the concurrent request handling. Sanitizers are unstable, so this needs
+// the nightly toolchain and rebuilds std with -Zbuild-std for
+// x86_64-unknown-linux-gnu so races inside std aren't missed or misreported
+func (m *JustMcp) TestThreadSanitizer(ctx context.Context, source *dagger.Directory) (string, error) {
+	container := m.nightlyContainer(source).
+		WithExec([]string{"rustup", "component", "add", "rust-src", "--toolchain", "nightly"}).
+		WithEnvVariable("RUSTFLAGS", "-Zsanitizer=thread").
+		WithEnvVariable("RUSTDOCFLAGS", "-Zsanitizer=thread").
+		// Sanitized artifacts can't be mixed with regular ones
+		WithEnvVariable("CARGO_TARGET_DIR", "/tmp/tsan-target")
+
+	fmt.Println("🧪 Running tests under ThreadSanitizer...")
+	passed, out := execCapture(ctx, container, []string{
+		"cargo", "+nightly", "test",
+		"-Zbuild-std",
+		"--target", "x86_64-unknown-linux-gnu",
+	})
+	if races := strings.Count(out, "WARNING: ThreadSanitizer"); races > 0 {
+		return out, fmt.Errorf("ThreadSanitizer reported %d data race(s)", races)
+	}
+	if !passed {
+		return out, fmt.Errorf("tests failed under ThreadSanitizer")
+	}
+	return out, nil
+}
+
+// Examples builds every example in the workspace and, with run set, runs
+// each one (or only those listed in examples), failing on any that don't build
+// or exit non-zero. Examples are found through cargo metadata, so both
+// examples/ files and [[example]] targets are covered
+func (m *JustMcp) Examples(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Run the examples after building them
+	// +optional
+	run bool,
+	// Names of the examples to run; empty runs all of them
+	// +optional
+	examples []string,
+) (string, error) {
+	container := withCargoCache(m.rustContainer(source), "examples", m.workdir()+"/target/examples")
+
+	out, err := container.
+		WithExec([]string{"cargo", "metadata", "--no-deps", "--format-version", "1"}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("cargo metadata failed: %w", err)
+	}
+	var metadata cargoMetadata
+	if err := json.Unmarshal([]byte(out), &metadata); err != nil {
+		return "", fmt.Errorf("failed to parse cargo metadata: %w", err)
+	}
+
+	// Example names are only unique within a package
+	type example struct{ pkg, name string }
+	var found []example
+	for _, pkg := range metadata.Packages {
+		for _, target := range pkg.Targets {
+			if slices.Contains(target.Kind, "example") {
+				found = append(found, example{pkg.Name, target.Name})
+			}
+		}
+	}
+	if len(found) == 0 {
+		return "⚠️  No examples found, skipping", nil
+	}
+	for _, name := range examples {
+		if !slices.ContainsFunc(found, func(e example) bool { return e.name == name }) {
+			return "", fmt.Errorf("unknown example %q", name)
+		}
+	}
+
+	fmt.Printf("📦 Building %d example(s)...\n", len(found))
+	if passed, out := execCapture(ctx, container, []string{"cargo", "build", "--examples", "--workspace"}); !passed {
+		return "", fmt.Errorf("examples failed to build:\n%s", out)
+	}
+	if !run {
+		return fmt.Sprintf("✅ Built %d example(s)", len(found)), nil
+	}
+
+	var report, failures []string
+	for _, e := range found {
+		if len(examples) > 0 && !slices.Contains(examples, e.name) {
+			continue
+		}
+		fmt.Printf("🧪 Running example %s...\n", e.name)
+		passed, out := execCapture(ctx, container, []string{"cargo", "run", "-p", e.pkg, "--example", e.name})
+		if passed {
+			report = append(report, "✅ "+e.name)
+		} else {
+			report = append(report, "❌ "+e.name)
+			failures = append(failures, fmt.Sprintf("%s:\n%s", e.name, strings.TrimSpace(out)))
+		}
+	}
+
+	if len(failures) > 0 {
+		return "", fmt.Errorf("%s\n\n%s", strings.Join(report, "\n"), strings.Join(failures, "\n\n"))
+	}
+	return strings.Join(report, "\n"), nil
+}
+
+// testContainer creates a container for running the test suite on a platform
+func (m *JustMcp) testContainer(source *dagger.Directory, platform string) *dagger.Container {
+	return dag.Container(dagger.ContainerOpts{Platform: dagger.Platform(platform)}).
 		From("rust:1.88.0").
 		WithDirectory("/src", source).
-		WithWorkdir("/src").
+		WithWorkdir(m.workdir()).
 		WithExec([]string{"rustup", "component", "add", "rustfmt", "clippy"}).
 		// Install just for tests
-		WithExec([]string{"sh", "-c", "curl -qsSf https://just.systems/install.sh | bash -s -- --to /usr/local/bin"})
-	
-	return container.
-		WithExec([]string{"cargo", "test"}). // TODO: Add option for verbose output?
+		With(m.withJust(platform)).
+		With(withCargoRegistryCache)
+}
+
+// libtestResult matches the per-test result lines of libtest's output
+var libtestResult = regexp.MustCompile(`(?m)^test (.+) \.\.\. (ok|FAILED|ignored)`)
+
+// libtestToTAP converts libtest's human-readable output into a TAP version 13
+// report, with ignored tests reported as skipped
+func libtestToTAP(output string) string {
+	var lines []string
+	for _, match := range libtestResult.FindAllStringSubmatch(output, -1) {
+		n := len(lines) + 1
+		switch match[2] {
+		case "ok":
+			lines = append(lines, fmt.Sprintf("ok %d - %s", n, match[1]))
+		case "FAILED":
+			lines = append(lines, fmt.Sprintf("not ok %d - %s", n, match[1]))
+		case "ignored":
+			lines = append(lines, fmt.Sprintf("ok %d - %s # SKIP ignored", n, match[1]))
+		}
+	}
+	return fmt.Sprintf("TAP version 13\n1..%d\n%s\n", len(lines), strings.Join(lines, "\n"))
+}
+
+// coreBacktraceScript runs the tests with core dumps enabled and, when they
+// fail, prints a backtrace for every core file left behind using the
+// executable recorded in the core
+const coreBacktraceScript = `ulimit -c unlimited
+cargo test && exit 0
+status=$?
+for core in $(find . /tmp -xdev -type f \( -name core -o -name 'core.[0-9]*' \) 2>/dev/null); do
+  exe=$(file -b "$core" | sed -n "s/.*execfn: '\\([^']*\\)'.*/\\1/p")
+  echo "=== backtrace for $core ($exe) ==="
+  rust-gdb -batch -ex 'thread apply all bt' "$exe" "$core" 2>&1
+done
+exit $status
+`
+
+// runTestsWithCoreCapture runs the test suite with core dumps enabled, adding
+// gdb backtraces of any crashed test binaries to the failure
+func runTestsWithCoreCapture(ctx context.Context, container *dagger.Container) (string, error) {
+	container = container.
+		WithExec([]string{"sh", "-c", "apt-get update && apt-get install -y gdb file"})
+
+	passed, out := execCapture(ctx, container, []string{"sh", "-c", coreBacktraceScript})
+	if !passed {
+		return "", fmt.Errorf("tests failed:\n%s", out)
+	}
+	return out, nil
+}
+
+// runTestsWithRetries runs the test suite through nextest so flaky tests are
+// retried, appending a summary of the tests that only passed on retry
+func runTestsWithRetries(ctx context.Context, container *dagger.Container, retries int) (string, error) {
+	container = container.
+		WithExec([]string{"cargo", "install", "cargo-nextest", "--locked"}).
+		WithExec([]string{"cargo", "nextest", "run", "--retries", strconv.Itoa(retries)})
+
+	// nextest reports progress on stderr
+	stderr, err := container.Stderr(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// nextest doesn't run doctests, so run them separately
+	doctests, err := container.
+		WithExec([]string{"cargo", "test", "--doc"}).
 		Stdout(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var flaky []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.Contains(line, "FLAKY") {
+			flaky = append(flaky, strings.TrimSpace(line))
+		}
+	}
+
+	out := stderr + doctests
+	if len(flaky) > 0 {
+		out += fmt.Sprintf("\n⚠️  %d test(s) needed retries:\n%s\n", len(flaky), strings.Join(flaky, "\n"))
+	}
+	return out, nil
 }
 
+// defaultTarpaulinImage is the official tarpaulin image used for coverage
+const defaultTarpaulinImage = "xd009642/tarpaulin:0.31.2"
+
 // Coverage generates code coverage report using tarpaulin
-func (m *JustMcp) Coverage(ctx context.Context, source *dagger.Directory) (*dagger.File, error) {
+func (m *JustMcp) Coverage(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Tarpaulin image to use, e.g. a newer tag or an internal mirror
+	// +optional
+	// +default="xd009642/tarpaulin:0.31.2"
+	tarpaulinImage string,
+	// Tarpaulin config file relative to the crate, e.g. tarpaulin.toml; its
+	// exclude-files are checked to be absent from the report
+	// +optional
+	config string,
+) (*dagger.File, error) {
+	if config == "" {
+		return m.tarpaulin(source, tarpaulinImage, "", "Html").
+			File("/coverage/tarpaulin-report.html"), nil
+	}
+
+	coverage := m.tarpaulin(source, tarpaulinImage, config, "Html", "Json")
+	if err := m.verifyTarpaulinExcludes(ctx, source, config, coverage.File("/coverage/tarpaulin-report.json")); err != nil {
+		return nil, err
+	}
+	return coverage.File("/coverage/tarpaulin-report.html"), nil
+}
+
+// tarpaulin runs cargo-tarpaulin, writing reports in the given formats to /coverage
+// An empty image selects defaultTarpaulinImage; config is an optional
+// tarpaulin config file relative to the crate
+func (m *JustMcp) tarpaulin(source *dagger.Directory, image string, config string, formats ...string) *dagger.Container {
+	if image == "" {
+		image = defaultTarpaulinImage
+	}
+
 	container := dag.Container().
-		From("xd009642/tarpaulin:0.27.3"). // Use official tarpaulin image
+		From(image).
 		WithDirectory("/src", source).
-		WithWorkdir("/src").
+		WithWorkdir(m.workdir()).
 		// Install just for tests
-		WithExec([]string{"sh", "-c", "curl -qsSf https://just.systems/install.sh | bash -s -- --to /usr/local/bin"})
-	
+		With(m.withJust("linux/amd64"))
+
+	args := []string{"cargo", "tarpaulin"}
+	for _, format := range formats {
+		args = append(args, "--out", format)
+	}
+	if config != "" {
+		args = append(args, "--config", path.Join(m.workdir(), config))
+	}
+	args = append(args,
+		"--output-dir", "/coverage",
+		"--skip-clean",
+		"--target-dir", "/tmp/tarpaulin-target",
+	)
+
+	// Generate coverage with security options disabled for container environment
 	return container.
-		// Generate coverage with security options disabled for container environment
-		WithExec([]string{
-			"cargo", "tarpaulin",
-			"--out", "Html",
-			"--output-dir", "/coverage",
-			"--skip-clean",
-			"--target-dir", "/tmp/tarpaulin-target",
-		}, dagger.ContainerWithExecOpts{
+		WithExec(args, dagger.ContainerWithExecOpts{
 			InsecureRootCapabilities: true,
-		}).
-		File("/coverage/tarpaulin-report.html"), nil
+		})
 }
 
 // Build creates a debug build
@@ -92,28 +593,50 @@ func (m *JustMcp) Build(
 	// +optional
 	// +default="linux/amd64"
 	platform string,
+	// Name of the [[bin]] target to build
+	// +optional
+	// +default="just-mcp"
+	bin string,
+	// Enable incremental compilation (CARGO_INCREMENTAL)
+	// +optional
+	// +default=true
+	incremental bool,
+	// C library to link against: gnu or musl (built with zig)
+	// +optional
+	// +default="gnu"
+	libc string,
+	// Cargo target directory relative to the crate, so builds don't contend
+	// for the build lock with stages running in parallel
+	// +optional
+	// +default="target/build"
+	targetDir string,
+	// Directory of locally patched crates, one subdirectory per crate named
+	// after its package, used as [patch.crates-io] overrides
+	// +optional
+	patches *dagger.Directory,
+	// Number of parallel cargo jobs; 0 lets cargo decide
+	// +optional
+	jobs int,
+	// Linker to use instead of the system default: mold or lld (linux gnu
+	// targets only)
+	// +optional
+	linker string,
 ) (*dagger.File, error) {
-	target := platformToTarget(platform)
-	
-	// Always use linux/amd64 container for cross-compilation
-	container := dag.Container().
-		From("rust:1.88.0").
-		WithDirectory("/src", source).
-		WithWorkdir("/src")
-
-	// For native x86_64 Linux, don't specify target to avoid issues
-	if platform == "linux/amd64" {
-		return container.
-			WithExec([]string{"cargo", "build"}).
-			File("/src/target/debug/just-mcp"), nil
+	if err := validateLibc(platform, libc); err != nil {
+		return nil, err
 	}
-	
-	// Setup cross-compilation for other targets
-	container = setupCrossCompilation(container, target)
-
-	return container.
-		WithExec([]string{"cargo", "build", "--target", target}).
-		File(fmt.Sprintf("/src/target/%s/debug/just-mcp", target)), nil
+	if err := validateLinker(platform, libc, linker); err != nil {
+		return nil, err
+	}
+	opts := buildOptions{bin: bin, incremental: incremental, libc: libc, targetDir: targetDir, jobs: jobs, linker: linker}
+	if patches != nil {
+		config, err := cargoPatchConfig(ctx, patches)
+		if err != nil {
+			return nil, err
+		}
+		opts.patch = withCargoPatches(patches, config)
+	}
+	return m.buildBinary(source, platform, opts), nil
 }
 
 // BuildRelease creates an optimized release build
@@ -123,95 +646,868 @@ func (m *JustMcp) BuildRelease(
 	// +optional
 	// +default="linux/amd64"
 	platform string,
+	// Name of the [[bin]] target to build
+	// +optional
+	// +default="just-mcp"
+	bin string,
+	// Enable incremental compilation (CARGO_INCREMENTAL); off by default for
+	// reproducible release builds
+	// +optional
+	incremental bool,
+	// C library to link against: gnu or musl (built with zig)
+	// +optional
+	// +default="gnu"
+	libc string,
+	// Cargo target directory relative to the crate, so builds don't contend
+	// for the build lock with stages running in parallel
+	// +optional
+	// +default="target/build"
+	targetDir string,
+	// Number of parallel cargo jobs; 0 lets cargo decide
+	// +optional
+	jobs int,
+	// Linker to use instead of the system default: mold or lld (linux gnu
+	// targets only)
+	// +optional
+	linker string,
+	// Release opt-level override: 0-3, s (small) or z (smallest); empty keeps
+	// the profile's setting
+	// +optional
+	optLevel string,
+	// Statically link glibc (+crt-static, gnu only). glibc discourages this:
+	// NSS lookups (users, hostnames) and iconv still dlopen shared glibc
+	// modules at runtime, which must match the build's glibc version. Prefer
+	// libc=musl for fully static binaries
+	// +optional
+	crtStatic bool,
 ) (*dagger.File, error) {
-	target := platformToTarget(platform)
-	
-	// Always use linux/amd64 container for cross-compilation
-	container := dag.Container().
-		From("rust:1.88.0").
-		WithDirectory("/src", source).
-		WithWorkdir("/src")
+	if err := validateLibc(platform, libc); err != nil {
+		return nil, err
+	}
+	if err := validateLinker(platform, libc, linker); err != nil {
+		return nil, err
+	}
+	if optLevel != "" && !slices.Contains([]string{"0", "1", "2", "3", "s", "z"}, optLevel) {
+		return nil, fmt.Errorf("unsupported opt-level %q: expected 0-3, s or z", optLevel)
+	}
+	if crtStatic && (libc == "musl" || !strings.HasPrefix(platform, "linux/")) {
+		return nil, fmt.Errorf("crtStatic is only supported for linux gnu builds, got %s with %s", platform, libc)
+	}
+
+	binary := m.buildBinary(source, platform, buildOptions{release: true, bin: bin, incremental: incremental, libc: libc, targetDir: targetDir, jobs: jobs, linker: linker, optLevel: optLevel, crtStatic: crtStatic})
+	if crtStatic {
+		if err := m.verifyStatic(ctx, binary, platform); err != nil {
+			return nil, err
+		}
+	}
+	return binary, nil
+}
+
+// verifyStatic checks a +crt-static build has no dynamic dependencies. ldd
+// can only load binaries for the host architecture, so cross-compiled ones
+// are checked through their ELF dynamic section instead
+func (m *JustMcp) verifyStatic(ctx context.Context, binary *dagger.File, platform string) error {
+	if platform != "linux/amd64" {
+		_, err := m.CheckDynamicDeps(ctx, binary, nil)
+		return err
+	}
+
+	fmt.Println("🔍 Checking the binary is statically linked...")
+	// ldd exits non-zero for static binaries, printing "not a dynamic executable"
+	_, out := execCapture(ctx,
+		dag.Container().
+			From("debian:bookworm-slim").
+			WithFile("/just-mcp", binary),
+		[]string{"ldd", "/just-mcp"})
+	if !strings.Contains(out, "not a dynamic executable") && !strings.Contains(out, "statically linked") {
+		return fmt.Errorf("crt-static binary still links shared libraries:\n%s", out)
+	}
+	return nil
+}
+
+// BuildFullMatrix builds every platform with both the debug and release
+// profiles, at most parallel builds at a time, as an exhaustive pre-release
+// compile check. Binaries are named <target>-<profile>; failures are
+// collected and reported together
+func (m *JustMcp) BuildFullMatrix(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Platforms to build
+	// +optional
+	// +default=["linux/amd64","linux/arm64"]
+	platforms []string,
+	// Maximum number of builds running at once
+	// +optional
+	// +default=4
+	parallel int,
+) (*dagger.Directory, error) {
+	if len(platforms) == 0 {
+		platforms = []string{"linux/amd64", "linux/arm64"}
+	}
+	if parallel <= 0 {
+		parallel = 4
+	}
+
+	type result struct {
+		name   string
+		binary *dagger.File
+		err    error
+	}
+
+	results := make(chan result, len(platforms)*2)
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for _, platform := range platforms {
+		for _, release := range []bool{false, true} {
+			profile := "debug"
+			if release {
+				profile = "release"
+			}
+			name := platformToTarget(platform) + "-" + profile
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+
+				fmt.Printf("📦 Building %s...\n", name)
+				binary, err := m.buildBinary(source, platform, buildOptions{release: release, incremental: !release}).Sync(ctx)
+				results <- result{name: name, binary: binary, err: err}
+			}()
+		}
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	binaries := dag.Directory()
+	var failures []string
+	for res := range results {
+		if res.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", res.name, res.err))
+		} else {
+			binaries = binaries.WithFile(res.name, res.binary)
+		}
+	}
+
+	if len(failures) > 0 {
+		slices.Sort(failures)
+		return nil, fmt.Errorf("build failures:\n%s", strings.Join(failures, "\n"))
+	}
+	return binaries, nil
+}
+
+// BuildPanicAbort creates a release build with RUSTFLAGS="-C panic=abort" and
+// reports its size against the default release build. The prebuilt std still
+// carries unwinding code; rebuilding it with -Zbuild-std=std,panic_abort
+// shrinks the binary further but requires the nightly toolchain
+func (m *JustMcp) BuildPanicAbort(
+	ctx context.Context,
+	source *dagger.Directory,
+	// +optional
+	// +default="linux/amd64"
+	platform string,
+	// Rebuild std with panic=abort using nightly's -Zbuild-std
+	// +optional
+	nightly bool,
+) (*dagger.File, error) {
+	opts := buildOptions{release: true, rustflags: "-C panic=abort"}
+	if nightly {
+		opts.buildStd = "std,panic_abort"
+	}
+	binary := m.buildBinary(source, platform, opts)
+
+	size, err := binary.Size(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("panic=abort build failed: %w", err)
+	}
+	baseline, err := m.buildBinary(source, platform, buildOptions{release: true}).Size(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("default release build failed: %w", err)
+	}
+
+	delta := size - baseline
+	fmt.Printf("📊 panic=abort binary is %d bytes vs %d bytes (%+d bytes, %+.1f%%)\n",
+		size, baseline, delta, float64(delta)*100/float64(baseline))
+	return binary, nil
+}
+
+// BuildVerbose runs a build and returns the full cargo output, even on success
+func (m *JustMcp) BuildVerbose(
+	ctx context.Context,
+	source *dagger.Directory,
+	// +optional
+	// +default="linux/amd64"
+	platform string,
+	// Build with the release profile instead of debug
+	// +optional
+	release bool,
+) (string, error) {
+	// Mirror the incremental defaults of Build and BuildRelease
+	container, _ := m.cargoBuild(source, platform, buildOptions{release: release, incremental: !release})
+
+	stdout, err := container.Stdout(ctx)
+	if err != nil {
+		return "", err
+	}
+	// Cargo reports compilation progress and warnings on stderr
+	stderr, err := container.Stderr(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return stdout + stderr, nil
+}
+
+// BuildSplitDebug creates a stripped release binary with its debug symbols in
+// a separate just-mcp.debug file linked via .gnu_debuglink, returning both in
+// a directory. Only ELF (Linux) targets are supported
+func (m *JustMcp) BuildSplitDebug(
+	ctx context.Context,
+	source *dagger.Directory,
+	// +optional
+	// +default="linux/amd64"
+	platform string,
+) (*dagger.Directory, error) {
+	// Cross toolchains ship prefixed binutils alongside their gcc
+	objcopyTools := map[string]string{
+		"linux/amd64": "objcopy",
+		"linux/arm64": "aarch64-linux-gnu-objcopy",
+	}
+	objcopy, ok := objcopyTools[platform]
+	if !ok {
+		return nil, fmt.Errorf("split debug info is only supported for ELF targets, got %s", platform)
+	}
+
+	container, binaryPath := m.cargoBuild(source, platform, buildOptions{release: true, debugInfo: true})
+
+	return container.
+		WithWorkdir("/out").
+		WithExec([]string{objcopy, "--only-keep-debug", binaryPath, "just-mcp.debug"}).
+		WithExec([]string{objcopy, "--strip-debug", "--add-gnu-debuglink=just-mcp.debug", binaryPath, "just-mcp"}).
+		Directory("/out"), nil
+}
+
+// BuildTimings runs cargo build --timings and returns the cargo-timing.html
+// report showing which crates dominate compile time
+func (m *JustMcp) BuildTimings(
+	ctx context.Context,
+	source *dagger.Directory,
+	// +optional
+	// +default="linux/amd64"
+	platform string,
+	// Build with the release profile instead of debug
+	// +optional
+	release bool,
+) (*dagger.File, error) {
+	args := []string{"cargo", "build", "--timings"}
+	if release {
+		args = append(args, "--release")
+	}
+
+	container := m.buildContainer(source)
+	if platform != "linux/amd64" {
+		target := platformToTarget(platform)
+		container = setupCrossCompilation(container, target)
+		args = append(args, "--target", target)
+	}
+
+	return container.
+		WithExec(args).
+		File(m.workdir() + "/target/cargo-timings/cargo-timing.html"), nil
+}
+
+// BuildPgo creates a profile-guided optimized release build
+// An instrumented binary is first run against the repository's justfile to
+// collect profile data, which is then fed into the final build. This only works
+// for native (non-cross) targets since the instrumented binary has to execute;
+// non-amd64 Linux platforms run under emulation
+func (m *JustMcp) BuildPgo(
+	ctx context.Context,
+	source *dagger.Directory,
+	// +optional
+	// +default="linux/amd64"
+	platform string,
+) (*dagger.File, error) {
+	if !strings.HasPrefix(platform, "linux/") {
+		return nil, fmt.Errorf("PGO builds require a native linux platform, got %s", platform)
+	}
+
+	container := dag.Container(dagger.ContainerOpts{Platform: dagger.Platform(platform)}).
+		From("rust:1.88.0").
+		WithDirectory("/src", source).
+		WithWorkdir(m.workdir()).
+		With(withCargoRegistryCache).
+		WithExec([]string{"rustup", "component", "add", "llvm-tools-preview"})
+
+	// Phase 1: instrumented build
+	container = container.
+		WithEnvVariable("RUSTFLAGS", "-Cprofile-generate=/tmp/pgo-data").
+		WithExec([]string{"cargo", "build", "--release"})
+
+	// Phase 2: run a representative workload (initialize + tools/list over the
+	// repository's own justfile) to generate profile data
+	container = container.
+		WithExec([]string{"target/release/just-mcp", "--watch-dir", "."}, dagger.ContainerWithExecOpts{
+			Stdin: mcpListToolsSession,
+		}).
+		WithExec([]string{"sh", "-c",
+			"$(rustc --print sysroot)/lib/rustlib/$(rustc -vV | sed -n 's/^host: //p')/bin/llvm-profdata " +
+				"merge -o /tmp/pgo-data/merged.profdata /tmp/pgo-data"})
+
+	// Phase 3: optimized build using the merged profile
+	return container.
+		WithEnvVariable("RUSTFLAGS", "-Cprofile-use=/tmp/pgo-data/merged.profdata").
+		WithExec([]string{"cargo", "build", "--release"}).
+		File(m.workdir() + "/target/release/just-mcp"), nil
+}
+
+// defaultArchiveImage is the pinned Alpine image release archives are built in
+const defaultArchiveImage = "alpine:3.20"
+
+// Package creates a release archive with binary and documentation (README, LICENSE by default)
+func (m *JustMcp) Package(
+	ctx context.Context,
+	source *dagger.Directory,
+	// +optional
+	// +default="linux/amd64"
+	platform string,
+	// +optional
+	// +default="v0.1.0"
+	version string,
+	// Additional files (e.g. CHANGELOG.md, completions, man pages) merged into the archive root
+	// +optional
+	extras *dagger.Directory,
+	// Include the just-mcp.1 man page under man/ in the archive
+	// +optional
+	includeManPage bool,
+	// Include bash, zsh and fish completion scripts under completions/ in the archive
+	// +optional
+	includeCompletions bool,
+	// Documentation files copied from source into the archive; missing ones are skipped
+	// +optional
+	// +default=["README.md","LICENSE"]
+	docFiles []string,
+	// Append the short git SHA to the archive name (for untagged CI builds)
+	// +optional
+	includeSha bool,
+	// Git commit SHA used when includeSha is set
+	// +optional
+	gitSha string,
+	// Include a THIRD-PARTY-LICENSES.txt summary of dependency licenses
+	// +optional
+	includeLicenseReport bool,
+	// Include a just-mcp.spdx license declaration for the binary
+	// +optional
+	includeSpdx bool,
+	// Image used to create the archive; pin it for reproducible archives
+	// +optional
+	// +default="alpine:3.20"
+	archiveImage string,
+	// Creation time recorded in generated metadata such as just-mcp.spdx, as Unix seconds
+	// +optional
+	sourceDateEpoch int,
+) (*dagger.File, error) {
+	if archiveImage == "" {
+		archiveImage = defaultArchiveImage
+	}
+
+	binary := m.buildBinary(source, platform, buildOptions{release: true})
+
+	label, err := archiveVersion(version, includeSha, gitSha)
+	if err != nil {
+		return nil, err
+	}
+	archiveName := fmt.Sprintf("just-mcp-%s-%s", label, platformToArchiveName(platform))
+
+	archiveDir := collectDocFiles(ctx, source, docFiles).
+		WithFile("just-mcp", binary)
+	if extras != nil {
+		archiveDir = archiveDir.WithDirectory(".", extras)
+	}
+
+	if includeCompletions {
+		completions, err := m.Completions(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		if entries, err := completions.Entries(ctx); err != nil {
+			return nil, err
+		} else if len(entries) > 0 {
+			archiveDir = archiveDir.WithDirectory("completions", completions)
+		}
+	}
+
+	if includeManPage {
+		manPage, err := m.ManPage(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		archiveDir = archiveDir.WithFile("man/just-mcp.1", manPage)
+	}
+
+	if includeLicenseReport {
+		report, err := m.LicenseReport(ctx, source, "text")
+		if err != nil {
+			return nil, err
+		}
+		archiveDir = archiveDir.WithFile("THIRD-PARTY-LICENSES.txt", report)
+	}
+
+	if includeSpdx {
+		spdx, err := m.BinaryLicense(ctx, source, sourceDateEpoch)
+		if err != nil {
+			return nil, err
+		}
+		archiveDir = archiveDir.WithFile("just-mcp.spdx", spdx)
+	}
+
+	container := dag.Container().
+		From(archiveImage).
+		WithExec([]string{"apk", "add", "--no-cache", "tar", "gzip", "zip"}).
+		WithDirectory("/archive", archiveDir)
+
+
+	return container.
+		WithWorkdir("/archive").
+		WithExec([]string{"tar", "czf", fmt.Sprintf("/%s.tar.gz", archiveName), "."}).
+		File(fmt.Sprintf("/%s.tar.gz", archiveName)), nil
+}
+
+// CI runs the complete CI pipeline (format, lint, test)
+func (m *JustMcp) CI(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Linux platforms to run tests on; non-native ones run under QEMU emulation
+	// +optional
+	platforms []string,
+) (string, error) {
+	return m.ci(ctx, source, platforms, &stageTimer{}, nil)
+}
+
+// CILogs runs the CI pipeline and returns each stage's full output as
+// numbered <stage>.log files, so complete logs survive CI log-size limits
+// A failing stage doesn't fail the call; the outcome is written to result.txt
+func (m *JustMcp) CILogs(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Linux platforms to run tests on; non-native ones run under QEMU emulation
+	// +optional
+	platforms []string,
+) (*dagger.Directory, error) {
+	logs := newStageLogs()
+	result, err := m.ci(ctx, source, platforms, &stageTimer{}, logs)
+	if err != nil {
+		result = "❌ " + err.Error()
+	}
+	return logs.directory().WithNewFile("result.txt", result+"\n"), nil
+}
+
+// ciSummary is the summary.json written by CIArtifacts
+type ciSummary struct {
+	Passed   bool             `json:"passed"`
+	Result   string           `json:"result"`
+	Coverage bool             `json:"coverage"`
+	Stages   []ciSummaryStage `json:"stages"`
+}
+
+// ciSummaryStage is a single stage entry in summary.json
+type ciSummaryStage struct {
+	Name       string `json:"name"`
+	Passed     bool   `json:"passed"`
+	DurationMs int64  `json:"duration_ms"`
+	Log        string `json:"log"`
+}
+
+// CIArtifacts runs the CI pipeline and returns its outputs as a directory for
+// Dagger-based CI: stage logs under logs/, the HTML coverage report under
+// coverage/ and a machine-readable summary.json. Like CILogs, a failing stage
+// doesn't fail the call; check passed in summary.json
+func (m *JustMcp) CIArtifacts(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Linux platforms to run tests on; non-native ones run under QEMU emulation
+	// +optional
+	platforms []string,
+) (*dagger.Directory, error) {
+	timer := &stageTimer{}
+	logs := newStageLogs()
+	summary := ciSummary{Passed: true}
+
+	result, err := m.ci(ctx, source, platforms, timer, logs)
+	if err != nil {
+		summary.Passed = false
+		result = err.Error()
+	}
+	summary.Result = result
+
+	artifacts := dag.Directory().WithDirectory("logs", logs.directory())
+
+	// Coverage is only worth reporting once the tests passed
+	if summary.Passed {
+		report, err := m.Coverage(ctx, source, "", "")
+		if err == nil {
+			_, err = report.Sync(ctx)
+		}
+		if err != nil {
+			fmt.Println("⚠️  Coverage generation failed (non-critical)")
+		} else {
+			artifacts = artifacts.WithFile("coverage/tarpaulin-report.html", report)
+			summary.Coverage = true
+		}
+	}
+
+	durations := timer.durations()
+	for _, stage := range logs.recorded() {
+		summary.Stages = append(summary.Stages, ciSummaryStage{
+			Name:       stage.name,
+			Passed:     stage.passed,
+			DurationMs: durations[stage.name].Milliseconds(),
+			Log:        "logs/" + stage.file,
+		})
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return artifacts.WithNewFile("summary.json", string(data)+"\n"), nil
+}
+
+// ci runs the CI stages in order, timing them with timer and recording their
+// output to logs if set
+func (m *JustMcp) ci(ctx context.Context, source *dagger.Directory, platforms []string, timer *stageTimer, logs *stageLogs) (string, error) {
+	defer func() { fmt.Print(timer.summary()) }()
+
+	// Prime the shared registry cache once instead of racing across stages
+	fmt.Println("📥 Fetching dependencies...")
+	done := timer.track("warm cache")
+	out, err := stageOutput(ctx, m.warmCacheExec(source))
+	done()
+	logs.record("warm cache", out, err)
+	if err != nil {
+		return "", fmt.Errorf("dependency fetch failed: %w", err)
+	}
+
+	// Run format check
+	fmt.Println("🔍 Checking code formatting...")
+	done = timer.track("format")
+	out, err = stageOutput(ctx, m.formatExec(source))
+	done()
+	logs.record("format", out, err)
+	if err != nil {
+		return "", fmt.Errorf("format check failed: %w", err)
+	}
+	
+	// Run clippy
+	fmt.Println("📋 Running clippy linter...")
+	done = timer.track("clippy")
+	out, err = stageOutput(ctx, m.lintExec(source, false, ""))
+	done()
+	logs.record("clippy", out, err)
+	if err != nil {
+		return "", fmt.Errorf("clippy failed: %w", err)
+	}
+	
+	// Run tests on Linux platforms only (macOS testing requires native runners)
+	if len(platforms) == 0 {
+		platforms = []string{"linux/amd64"}
+	}
+	for _, platform := range platforms {
+		fmt.Printf("🧪 Running tests on %s...\n", platform)
+		done = timer.track("test " + platform)
+		container, err := m.testSetup(ctx, source, platform, "target/test", nil, 0)
+		if err == nil {
+			out, err = stageOutput(ctx, container.WithExec([]string{"cargo", "test"}))
+		}
+		done()
+		logs.record("test "+platform, out, err)
+		if err != nil {
+			return "", fmt.Errorf("tests failed on %s: %w", platform, err)
+		}
+	}
+	
+	// Generate coverage on Linux
+	fmt.Println("📊 Generating code coverage...")
+	done = timer.track("coverage")
+	// Same run as Coverage, evaluated here so tarpaulin is timed and its output logged
+	out, err = stageOutput(ctx, m.tarpaulin(source, "", "", "Html"))
+	done()
+	logs.record("coverage", out, err)
+	if err != nil {
+		fmt.Println("⚠️  Coverage generation failed (non-critical)")
+	}
+	
+	return "✅ CI pipeline completed successfully!", nil
+}
+
+// cargoMetadata is the subset of `cargo metadata` output we care about
+type cargoMetadata struct {
+	Packages []struct {
+		Name         string `json:"name"`
+		Version      string `json:"version"`
+		Edition      string `json:"edition"`
+		License      string `json:"license"`
+		Repository   string `json:"repository"`
+		ManifestPath string `json:"manifest_path"`
+		Targets      []struct {
+			Name string   `json:"name"`
+			Kind []string `json:"kind"`
+		} `json:"targets"`
+	} `json:"packages"`
+}
+
+// changedFiles lists the files changed between baseRef's merge base and the
+// working tree, relative to the crate. The container must be set up by withGit
+func (m *JustMcp) changedFiles(ctx context.Context, container *dagger.Container, baseRef string) ([]string, error) {
+	// --relative limits the diff to the crate and makes paths relative to it
+	out, err := container.
+		WithExec([]string{"git", "diff", "--name-only", "--diff-filter=d", "--relative", "--merge-base", baseRef}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff against %s: %w", baseRef, err)
+	}
+	return strings.Fields(out), nil
+}
+
+// readCargoMetadata runs `cargo metadata` for the workspace in container
+func readCargoMetadata(ctx context.Context, container *dagger.Container) (cargoMetadata, error) {
+	var metadata cargoMetadata
+	out, err := container.
+		WithExec([]string{"cargo", "metadata", "--no-deps", "--format-version", "1"}).
+		Stdout(ctx)
+	if err != nil {
+		return metadata, fmt.Errorf("cargo metadata failed: %w", err)
+	}
+	if err := json.Unmarshal([]byte(out), &metadata); err != nil {
+		return metadata, fmt.Errorf("failed to parse cargo metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// owningPackage returns the index of the workspace package containing file,
+// the one with the deepest manifest directory above it, or -1 if none does
+func (m *JustMcp) owningPackage(metadata cargoMetadata, file string) int {
+	owner, ownerDir := -1, ""
+	for i, pkg := range metadata.Packages {
+		dir := strings.TrimPrefix(path.Dir(pkg.ManifestPath), m.workdir())
+		dir = strings.TrimPrefix(dir, "/")
+		if dir != "" && !strings.HasPrefix(file, dir+"/") {
+			continue
+		}
+		if owner < 0 || len(dir) > len(ownerDir) {
+			owner, ownerDir = i, dir
+		}
+	}
+	return owner
+}
+
+// changedPackages maps changed files to the workspace packages containing
+// them (see owningPackage). With libOnly, packages without a library target
+// are skipped
+func (m *JustMcp) changedPackages(ctx context.Context, container *dagger.Container, files []string, libOnly bool) ([]string, error) {
+	metadata, err := readCargoMetadata(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	var packages []string
+	for _, file := range files {
+		index := m.owningPackage(metadata, file)
+		if index < 0 {
+			continue
+		}
+		owner, hasLib := metadata.Packages[index], false
+		for _, target := range owner.Targets {
+			hasLib = hasLib || slices.Contains(target.Kind, "lib")
+		}
+		if (libOnly && !hasLib) || slices.Contains(packages, owner.Name) {
+			continue
+		}
+		packages = append(packages, owner.Name)
+	}
+	return packages, nil
+}
+
+// fullCITriggers are files whose changes can affect every crate target, so
+// CIChanged falls back to the full pipeline when any of them change
+var fullCITriggers = []string{"Cargo.toml", "Cargo.lock", "build.rs", "rust-toolchain.toml"}
+
+// CIChanged runs a CI pass scoped to the Rust files changed since baseRef:
+// rustfmt checks only those files, using their package's edition, clippy
+// only reports diagnostics in them and tests run only for the packages
+// containing them. Falls back to the full CI when more than maxFiles files
+// changed or a manifest or build script changed
+// Source must include .git
+func (m *JustMcp) CIChanged(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Git ref to diff against, e.g. origin/main
+	baseRef string,
+	// Run the full CI when more files than this changed
+	// +optional
+	// +default=50
+	maxFiles int,
+) (string, error) {
+	container, err := withGit(ctx, m.buildContainer(source), source)
+	if err != nil {
+		return "", err
+	}
+	changed, err := m.changedFiles(ctx, container, baseRef)
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range changed {
+		if slices.Contains(fullCITriggers, path.Base(file)) {
+			fmt.Printf("⚠️  %s changed, running full CI\n", file)
+			return m.CI(ctx, source, nil)
+		}
+	}
+	if len(changed) > maxFiles {
+		fmt.Printf("⚠️  %d files changed (max %d), running full CI\n", len(changed), maxFiles)
+		return m.CI(ctx, source, nil)
+	}
+
+	var rsFiles []string
+	for _, file := range changed {
+		if strings.HasSuffix(file, ".rs") {
+			rsFiles = append(rsFiles, file)
+		}
+	}
+	if len(rsFiles) == 0 {
+		return fmt.Sprintf("✅ No Rust files changed since %s", baseRef), nil
+	}
+	fmt.Printf("🔎 %d Rust file(s) changed since %s\n", len(rsFiles), baseRef)
+
+	fmt.Println("🔍 Checking code formatting...")
+	// cargo fmt always checks every target of the package, so call rustfmt
+	// directly with the edition of the package each file belongs to
+	metadata, err := readCargoMetadata(ctx, container)
+	if err != nil {
+		return "", err
+	}
+	editions := map[string][]string{}
+	for _, file := range rsFiles {
+		// rustfmt's own default for files outside any package
+		edition := "2015"
+		if index := m.owningPackage(metadata, file); index >= 0 && metadata.Packages[index].Edition != "" {
+			edition = metadata.Packages[index].Edition
+		}
+		editions[edition] = append(editions[edition], file)
+	}
+	var editionOrder []string
+	for edition := range editions {
+		editionOrder = append(editionOrder, edition)
+	}
+	slices.Sort(editionOrder)
+	for _, edition := range editionOrder {
+		args := append([]string{"rustfmt", "--check", "--edition", edition}, editions[edition]...)
+		if passed, out := execCapture(ctx, container, args); !passed {
+			return "", fmt.Errorf("format check failed:\n%s", out)
+		}
+	}
+
+	fmt.Println("📋 Running clippy linter...")
+	stream, err := withCargoCache(container, "clippy", m.workdir()+"/target/clippy").
+		WithExec([]string{"cargo", "clippy", "--message-format=json"}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("clippy failed: %w", err)
+	}
+	var findings []string
+	for _, line := range strings.Split(stream, "\n") {
+		var msg clippyMessage
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &msg) != nil || msg.Reason != "compiler-message" {
+			continue
+		}
+		for _, span := range msg.Message.Spans {
+			if span.IsPrimary && slices.Contains(rsFiles, span.FileName) {
+				findings = append(findings, fmt.Sprintf("%s: %s: %s", span.FileName, msg.Message.Level, msg.Message.Message))
+			}
+		}
+	}
+	if len(findings) > 0 {
+		return "", fmt.Errorf("clippy found %d issue(s) in changed files:\n%s", len(findings), strings.Join(findings, "\n"))
+	}
 
-	binaryName := "just-mcp"
-	
-	// For native x86_64 Linux, don't specify target to avoid issues
-	if platform == "linux/amd64" {
-		return container.
-			WithExec([]string{"cargo", "build", "--release"}).
-			File("/src/target/release/" + binaryName), nil
+	packages, err := m.changedPackages(ctx, container, rsFiles, false)
+	if err != nil {
+		return "", err
 	}
-	
-	// Setup cross-compilation for other targets
-	container = setupCrossCompilation(container, target)
 
-	return container.
-		WithExec([]string{"cargo", "build", "--release", "--target", target}).
-		File(fmt.Sprintf("/src/target/%s/release/%s", target, binaryName)), nil
+	args := []string{"cargo", "test"}
+	for _, pkg := range packages {
+		args = append(args, "-p", pkg)
+	}
+	fmt.Printf("🧪 Running tests for %s...\n", strings.Join(packages, ", "))
+	if _, err := m.testContainer(source, "linux/amd64").WithExec(args).Sync(ctx); err != nil {
+		return "", fmt.Errorf("tests failed: %w", err)
+	}
+
+	return fmt.Sprintf("✅ Changed-files CI passed for %d file(s) in %s", len(rsFiles), strings.Join(packages, ", ")), nil
 }
 
-// Package creates a release archive with binary, README, and LICENSE
-func (m *JustMcp) Package(
+// DocTestChanged runs doctests only for the packages containing Rust or
+// Markdown files changed since baseRef (Markdown can be pulled into rustdoc
+// with include_str!). Packages without a library have no doctests and are
+// skipped. Runs every doctest when a manifest or build script changed
+// Source must include .git
+func (m *JustMcp) DocTestChanged(
 	ctx context.Context,
 	source *dagger.Directory,
-	// +optional
-	// +default="linux/amd64"
-	platform string,
-	// +optional
-	// +default="v0.1.0"
-	version string,
-) (*dagger.File, error) {
-	binary, err := m.BuildRelease(ctx, source, platform)
+	// Git ref to diff against, e.g. origin/main
+	baseRef string,
+) (string, error) {
+	container, err := withGit(ctx, m.buildContainer(source), source)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-
-	archiveName := fmt.Sprintf("just-mcp-%s-%s", version, platformToArchiveName(platform))
-	
-	container := dag.Container().
-		From("alpine:latest").
-		WithExec([]string{"apk", "add", "--no-cache", "tar", "gzip", "zip"}).
-		WithDirectory("/archive", dag.Directory().
-			WithFile("just-mcp", binary).
-			WithFile("README.md", source.File("README.md")).
-			WithFile("LICENSE", source.File("LICENSE")))
-
-
-	return container.
-		WithWorkdir("/archive").
-		WithExec([]string{"tar", "czf", fmt.Sprintf("/%s.tar.gz", archiveName), "."}).
-		File(fmt.Sprintf("/%s.tar.gz", archiveName)), nil
-}
-
-// CI runs the complete CI pipeline (format, lint, test)
-func (m *JustMcp) CI(ctx context.Context, source *dagger.Directory) (string, error) {
-	// Run format check
-	fmt.Println("🔍 Checking code formatting...")
-	if _, err := m.Format(ctx, source); err != nil {
-		return "", fmt.Errorf("format check failed: %w", err)
+	changed, err := m.changedFiles(ctx, container, baseRef)
+	if err != nil {
+		return "", err
 	}
-	
-	// Run clippy
-	fmt.Println("📋 Running clippy linter...")
-	if _, err := m.Lint(ctx, source); err != nil {
-		return "", fmt.Errorf("clippy failed: %w", err)
+
+	args := []string{"cargo", "test", "--doc"}
+	label := "the workspace"
+	var docFiles []string
+	full := false
+	for _, file := range changed {
+		if slices.Contains(fullCITriggers, path.Base(file)) {
+			fmt.Printf("⚠️  %s changed, running all doctests\n", file)
+			full = true
+			break
+		}
+		if strings.HasSuffix(file, ".rs") || strings.HasSuffix(file, ".md") {
+			docFiles = append(docFiles, file)
+		}
 	}
-	
-	// Run tests on Linux platforms only (cross-platform testing requires native runners)
-	platforms := []string{"linux/amd64"}
-	for _, platform := range platforms {
-		fmt.Printf("🧪 Running tests on %s...\n", platform)
-		if _, err := m.Test(ctx, source, platform); err != nil {
-			return "", fmt.Errorf("tests failed on %s: %w", platform, err)
+
+	if full {
+		args = append(args, "--workspace")
+	} else {
+		packages, err := m.changedPackages(ctx, container, docFiles, true)
+		if err != nil {
+			return "", err
+		}
+		if len(packages) == 0 {
+			return fmt.Sprintf("✅ No library sources changed since %s", baseRef), nil
 		}
+		for _, pkg := range packages {
+			args = append(args, "-p", pkg)
+		}
+		label = strings.Join(packages, ", ")
 	}
-	
-	// Generate coverage on Linux
-	fmt.Println("📊 Generating code coverage...")
-	if _, err := m.Coverage(ctx, source); err != nil {
-		fmt.Println("⚠️  Coverage generation failed (non-critical)")
+
+	fmt.Printf("🧪 Running doctests for %s...\n", label)
+	if _, err := withCargoCache(container, "doctest", m.workdir()+"/target/doctest").WithExec(args).Sync(ctx); err != nil {
+		return "", fmt.Errorf("doctests failed: %w", err)
 	}
-	
-	return "✅ CI pipeline completed successfully!", nil
+	return fmt.Sprintf("✅ Doctests passed for %s", label), nil
 }
 
 // Release builds releases for Linux platforms only
@@ -236,20 +1532,46 @@ func (m *JustMcp) Release(
 	for _, p := range platforms {
 		fmt.Printf("📦 Building release for %s...\n", p.name)
 		
-		archive, err := m.Package(ctx, source, p.platform, version)
+		archive, err := m.Package(ctx, source, p.platform, version, nil, false, false, nil, false, "", false, false, "", 0)
 		if err != nil {
 			return nil, fmt.Errorf("failed to package %s: %w", p.name, err)
 		}
 		
 		releases = append(releases, archive)
 	}
+
+	// Statically linked musl variants are cross-compiled with zigbuild
+	for _, target := range muslTargets {
+		fmt.Printf("📦 Building release for %s...\n", target)
+
+		artifact, err := m.ZigbuildSingle(ctx, source, target, version, nil, false, "", "", "", "", false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to package %s: %w", target, err)
+		}
+
+		releases = append(releases, artifact.Archive)
+	}
 	
 	return releases, nil
 }
 
+// muslTargets are the statically linked Linux targets shipped alongside the
+// glibc ones; their triples give the archives a distinct -musl suffix
+var muslTargets = []string{
+	"x86_64-unknown-linux-musl",
+	"aarch64-unknown-linux-musl",
+}
+
 
 // Helper functions
 
+// mcpListToolsSession is a minimal MCP stdio session: the initialize handshake
+// followed by a tools/list request
+const mcpListToolsSession = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"dagger","version":"0.0.0"}}}
+{"jsonrpc":"2.0","method":"notifications/initialized"}
+{"jsonrpc":"2.0","id":2,"method":"tools/list","params":{}}
+`
+
 func platformToTarget(platform string) string {
 	targets := map[string]string{
 		"linux/amd64":   "x86_64-unknown-linux-gnu",
@@ -268,6 +1590,357 @@ func platformToArchiveName(platform string) string {
 	return platformToTarget(platform)
 }
 
+// justBinary downloads a statically linked (musl) just for a Linux platform
+// with the official installer. Dagger caches the result, so the download
+// happens once and the file is copied into every container that needs just
+// An empty version installs the latest release
+func justBinary(version string, platform string) *dagger.File {
+	target := strings.TrimSuffix(platformToTarget(platform), "-gnu") + "-musl"
+	install := "curl -qsSf https://just.systems/install.sh | bash -s -- --to /usr/local/bin --target " + target
+	if version != "" {
+		install += " --tag " + version
+	}
+	return dag.Container().
+		From(defaultArchiveImage).
+		WithExec([]string{"apk", "add", "--no-cache", "bash", "curl"}).
+		WithExec([]string{"sh", "-c", install}).
+		File("/usr/local/bin/just")
+}
+
+// withJust copies the configured just release for the platform onto PATH
+func (m *JustMcp) withJust(platform string) func(*dagger.Container) *dagger.Container {
+	return func(container *dagger.Container) *dagger.Container {
+		return container.WithFile("/usr/local/bin/just", justBinary(m.JustVersion, platform), dagger.ContainerWithFileOpts{
+			Permissions: 0o755,
+		})
+	}
+}
+
+// withCargoRegistryCache mounts the cargo registry cache shared by all stages
+func withCargoRegistryCache(container *dagger.Container) *dagger.Container {
+	return container.
+		WithMountedCache("/usr/local/cargo/registry", dag.CacheVolume("just-mcp-cargo-registry"))
+}
+
+// withCargoCache mounts the shared cargo registry cache and a stage-specific
+// target cache, pointing CARGO_TARGET_DIR at the latter
+func withCargoCache(container *dagger.Container, stage string, targetDir string) *dagger.Container {
+	return withCargoRegistryCache(container).
+		WithMountedCache(targetDir, dag.CacheVolume("just-mcp-target-"+stage)).
+		WithEnvVariable("CARGO_TARGET_DIR", targetDir)
+}
+
+// withGit prepares a container holding source at /src for git commands,
+// failing with a clear error when source was passed without its .git
+// directory. Shallow clones are allowed but only resolve refs within the
+// fetched history, so they get a warning
+func withGit(ctx context.Context, container *dagger.Container, source *dagger.Directory) (*dagger.Container, error) {
+	entries, err := source.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(entries, ".git") {
+		return nil, fmt.Errorf("source must include .git history")
+	}
+	if shallow, err := source.Glob(ctx, ".git/shallow"); err == nil && len(shallow) > 0 {
+		fmt.Println("⚠️  Source is a shallow clone, refs outside the fetched history won't resolve")
+	}
+
+	// The source is owned by another user inside the container
+	return container.
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "*"}), nil
+}
+
+// cargoPatchConfig renders a cargo config with a [patch.crates-io] entry for
+// each crate directory in patches, as mounted by withCargoPatches
+func cargoPatchConfig(ctx context.Context, patches *dagger.Directory) (string, error) {
+	crates, err := patches.Entries(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list patched crates: %w", err)
+	}
+	if len(crates) == 0 {
+		return "", fmt.Errorf("patches directory is empty")
+	}
+
+	var config strings.Builder
+	config.WriteString("[patch.crates-io]\n")
+	for _, crate := range crates {
+		crate = strings.TrimSuffix(crate, "/")
+		fmt.Fprintf(&config, "%s = { path = %q }\n", crate, "/patches/"+crate)
+	}
+	return config.String(), nil
+}
+
+// withCargoPatches mounts patched crates at /patches and registers them as
+// [patch.crates-io] overrides in the global cargo config, leaving the
+// repository's Cargo.toml untouched
+func withCargoPatches(patches *dagger.Directory, config string) func(*dagger.Container) *dagger.Container {
+	return func(container *dagger.Container) *dagger.Container {
+		return container.
+			WithMountedDirectory("/patches", patches).
+			WithNewFile("/usr/local/cargo/config.toml", config)
+	}
+}
+
+// buildOptions tweaks how cargoBuild invokes cargo
+type buildOptions struct {
+	// Build with the release profile instead of debug
+	release bool
+	// [[bin]] target to build; defaults to just-mcp
+	bin string
+	// Enable incremental compilation
+	incremental bool
+	// Emit full debug info even for release builds
+	debugInfo bool
+	// C library to link against: "gnu" (default) or "musl"
+	libc string
+	// Extra RUSTFLAGS for the build
+	rustflags string
+	// Crates to rebuild std from with -Zbuild-std (e.g. "std,panic_abort");
+	// requires nightly
+	buildStd string
+	// Cargo target directory relative to the crate; defaults to target
+	targetDir string
+	// Applies [patch.crates-io] overrides, see withCargoPatches
+	patch func(*dagger.Container) *dagger.Container
+	// Number of parallel cargo jobs; 0 lets cargo decide
+	jobs int
+	// Linker cc uses via -fuse-ld (mold or lld); empty for the system default
+	linker string
+	// Release profile opt-level override; empty keeps Cargo.toml's setting
+	optLevel string
+	// Statically link the C runtime (+crt-static); gnu targets only
+	crtStatic bool
+}
+
+// buildBinary builds the platform binary and returns it
+func (m *JustMcp) buildBinary(source *dagger.Directory, platform string, opts buildOptions) *dagger.File {
+	container, binaryPath := m.cargoBuild(source, platform, opts)
+	return container.File(binaryPath)
+}
+
+// cargoBuild runs cargo build for the platform, returning the container the
+// build ran in along with the path of the produced binary
+func (m *JustMcp) cargoBuild(source *dagger.Directory, platform string, opts buildOptions) (*dagger.Container, string) {
+	target := platformToTarget(platform)
+
+	bin := opts.bin
+	if bin == "" {
+		bin = "just-mcp"
+	}
+
+	profile := "debug"
+	args := []string{"cargo", "build", "--bin", bin}
+	if opts.release {
+		profile = "release"
+		args = append(args, "--release")
+	}
+	if opts.jobs > 0 {
+		args = append(args, "-j", strconv.Itoa(opts.jobs))
+	}
+
+	incremental := "0"
+	if opts.incremental {
+		incremental = "1"
+	}
+
+	targetDir := path.Join(m.workdir(), "target")
+	if opts.targetDir != "" {
+		targetDir = path.Join(m.workdir(), opts.targetDir)
+	}
+
+	// Always use linux/amd64 container for cross-compilation
+	image := "rust:1.88.0"
+	if opts.libc == "musl" {
+		// zig provides a musl sysroot and linker for every architecture
+		image = "ghcr.io/rust-cross/cargo-zigbuild:latest"
+		target = strings.TrimSuffix(target, "-gnu") + "-musl"
+		args[1] = "zigbuild"
+	}
+	container := dag.Container().
+		From(image).
+		WithDirectory("/src", source).
+		WithWorkdir(m.workdir()).
+		With(withCargoRegistryCache).
+		WithEnvVariable("CARGO_INCREMENTAL", incremental)
+	if opts.debugInfo {
+		container = container.WithEnvVariable("CARGO_PROFILE_RELEASE_DEBUG", "true")
+	}
+	if opts.optLevel != "" {
+		container = container.WithEnvVariable("CARGO_PROFILE_RELEASE_OPT_LEVEL", opts.optLevel)
+	}
+	rustflags := opts.rustflags
+	if opts.linker != "" {
+		container = container.
+			WithExec([]string{"apt-get", "update"}).
+			WithExec([]string{"apt-get", "install", "-y", opts.linker})
+		rustflags = strings.TrimSpace(rustflags + " -C link-arg=-fuse-ld=" + opts.linker)
+	}
+	if opts.crtStatic {
+		rustflags = strings.TrimSpace(rustflags + " -C target-feature=+crt-static")
+	}
+	if rustflags != "" {
+		container = container.WithEnvVariable("RUSTFLAGS", rustflags)
+	}
+	if opts.targetDir != "" {
+		container = container.WithEnvVariable("CARGO_TARGET_DIR", targetDir)
+	}
+	if opts.patch != nil {
+		container = container.With(opts.patch)
+	}
+	if opts.buildStd != "" {
+		container = container.
+			WithExec([]string{"rustup", "toolchain", "install", "nightly", "--profile", "minimal", "--component", "rust-src"})
+		args = append([]string{"cargo", "+nightly"}, args[1:]...)
+		args = append(args, "-Zbuild-std="+opts.buildStd)
+	}
+
+	if opts.libc == "musl" {
+		return container.
+				WithExec([]string{"rustup", "target", "add", target}).
+				WithExec(append(args, "--target", target)),
+			fmt.Sprintf("%s/%s/%s/%s", targetDir, target, profile, bin)
+	}
+
+	// For native x86_64 Linux, don't specify target to avoid issues; build-std
+	// always needs an explicit target though, and so does crt-static, or the
+	// flag also applies to build scripts and proc macros, which can't be static
+	if platform == "linux/amd64" && opts.buildStd == "" && !opts.crtStatic {
+		return container.WithExec(args), fmt.Sprintf("%s/%s/%s", targetDir, profile, bin)
+	}
+
+	// Setup cross-compilation for other targets
+	container = setupCrossCompilation(container, target)
+
+	return container.WithExec(append(args, "--target", target)),
+		fmt.Sprintf("%s/%s/%s/%s", targetDir, target, profile, bin)
+}
+
+// archiveVersion returns the version label used in archive names, optionally
+// suffixed with the short git SHA
+func archiveVersion(version string, includeSha bool, gitSha string) (string, error) {
+	if !includeSha {
+		return version, nil
+	}
+	if gitSha == "" {
+		return "", fmt.Errorf("includeSha requires gitSha to be set")
+	}
+
+	short := gitSha
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if version == "" {
+		return short, nil
+	}
+	return version + "-" + short, nil
+}
+
+// collectDocFiles copies the named documentation files from source into a new
+// directory, warning about and skipping any that don't exist
+func collectDocFiles(ctx context.Context, source *dagger.Directory, names []string) *dagger.Directory {
+	if len(names) == 0 {
+		names = []string{"README.md", "LICENSE"}
+	}
+
+	docs := dag.Directory()
+	for _, name := range names {
+		// Listing fails if the parent directory itself is missing
+		entries, err := source.Entries(ctx, dagger.DirectoryEntriesOpts{Path: path.Dir(name)})
+		if err != nil || !slices.Contains(entries, path.Base(name)) {
+			fmt.Printf("⚠️  %s not found in source, skipping\n", name)
+			continue
+		}
+		docs = docs.WithFile(name, source.File(name))
+	}
+	return docs
+}
+
+// isWindowsTarget reports whether a Rust target triple builds Windows binaries
+func isWindowsTarget(target string) bool {
+	return strings.Contains(target, "-windows-")
+}
+
+// archiveExtension returns the release archive extension for a target
+func archiveExtension(target string) string {
+	if isWindowsTarget(target) {
+		return ".zip"
+	}
+	return ".tar.gz"
+}
+
+// verifyWindowsBinary checks with file(1) that a Windows binary is a PE32+
+// image for the target's architecture
+func verifyWindowsBinary(ctx context.Context, binary *dagger.File, target string) error {
+	out, err := dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "file"}).
+		WithFile("/just-mcp.exe", binary).
+		WithExec([]string{"file", "-b", "/just-mcp.exe"}).
+		Stdout(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s binary: %w", target, err)
+	}
+
+	arch := "x86-64"
+	if strings.HasPrefix(target, "aarch64-") {
+		arch = "Aarch64"
+	}
+	if !strings.Contains(out, "PE32+") || !strings.Contains(out, arch) {
+		return fmt.Errorf("%s binary is not a PE32+ %s image: %s", target, arch, strings.TrimSpace(out))
+	}
+	return nil
+}
+
+// sha256Of returns the hex-encoded SHA256 digest of a file
+func sha256Of(ctx context.Context, file *dagger.File) (string, error) {
+	out, err := dag.Container().
+		From("alpine:latest").
+		WithFile("/file", file).
+		WithExec([]string{"sha256sum", "/file"}).
+		Stdout(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("unexpected sha256sum output: %q", out)
+	}
+	return fields[0], nil
+}
+
+// validateLibc checks that libc names a supported C library for the platform
+func validateLibc(platform, libc string) error {
+	switch libc {
+	case "", "gnu":
+		return nil
+	case "musl":
+		if !strings.HasPrefix(platform, "linux/") {
+			return fmt.Errorf("musl builds are only supported for linux platforms, got %s", platform)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported libc %q: expected gnu or musl", libc)
+}
+
+// validateLinker checks the linker can be used for the platform. mold and lld
+// are passed to gcc with -fuse-ld, so they work for the linux gnu targets
+// (x86_64 and aarch64, including the aarch64 cross gcc); musl builds link
+// with zig's own lld and macOS targets aren't linked with gcc at all
+func validateLinker(platform, libc, linker string) error {
+	switch linker {
+	case "":
+		return nil
+	case "mold", "lld":
+		if !strings.HasPrefix(platform, "linux/") || libc == "musl" {
+			return fmt.Errorf("%s is only supported for linux gnu builds, got %s with %s", linker, platform, libc)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported linker %q: expected mold or lld", linker)
+}
+
 // setupCrossCompilation configures the container for cross-compilation
 func setupCrossCompilation(container *dagger.Container, target string) *dagger.Container {
 	// Always add the target
@@ -293,6 +1966,18 @@ func setupCrossCompilation(container *dagger.Container, target string) *dagger.C
 	}
 }
 
+// ZigbuildArtifact is a release archive produced by ZigbuildSingle
+type ZigbuildArtifact struct {
+	// Rust target triple the archive was built for
+	Target string
+	// File name of the release archive
+	ArchiveName string
+	// The release archive
+	Archive *dagger.File
+	// SHA256 of the binary, verified against the copy inside the archive
+	BinarySha256 string
+}
+
 // ZigbuildSingle builds a release for a single platform using cargo-zigbuild
 // This provides cross-compilation support for macOS from Linux
 func (m *JustMcp) ZigbuildSingle(
@@ -302,12 +1987,48 @@ func (m *JustMcp) ZigbuildSingle(
 	// +optional
 	// +default="v0.1.0"
 	version string,
-) (*dagger.File, error) {
+	// Documentation files copied from source into the archive; missing ones are skipped
+	// +optional
+	// +default=["README.md","LICENSE"]
+	docFiles []string,
+	// Append the short git SHA to the archive name (for untagged CI builds)
+	// +optional
+	includeSha bool,
+	// Git commit SHA used when includeSha is set
+	// +optional
+	gitSha string,
+	// Image used to create the archive; pin it for reproducible archives
+	// +optional
+	// +default="alpine:3.20"
+	archiveImage string,
+	// Comma-separated cargo features to enable on top of the defaults
+	// +optional
+	features string,
+	// Cargo profile to build with
+	// +optional
+	// +default="release"
+	profile string,
+	// Strip symbols from the binary (sets strip for the profile)
+	// +optional
+	strip bool,
+) (*ZigbuildArtifact, error) {
+	if archiveImage == "" {
+		archiveImage = defaultArchiveImage
+	}
+	if profile == "" {
+		profile = "release"
+	}
+
+	label, err := archiveVersion(version, includeSha, gitSha)
+	if err != nil {
+		return nil, err
+	}
+
 	// Use the official cargo-zigbuild Docker image which includes macOS SDK
 	container := dag.Container().
 		From("ghcr.io/rust-cross/cargo-zigbuild:latest").
 		WithDirectory("/src", source).
-		WithWorkdir("/src")
+		WithWorkdir(m.workdir())
 	
 	// Handle universal2-apple-darwin specially - it needs both Apple targets
 	if target == "universal2-apple-darwin" {
@@ -321,33 +2042,148 @@ func (m *JustMcp) ZigbuildSingle(
 	}
 	
 	fmt.Printf("📦 Building release for %s...\n", target)
+	args := []string{"cargo", "zigbuild", "--profile", profile, "--target", target}
+	if features != "" {
+		args = append(args, "--features", features)
+	}
+	if strip {
+		container = container.
+			WithEnvVariable(fmt.Sprintf("CARGO_PROFILE_%s_STRIP", strings.ToUpper(strings.ReplaceAll(profile, "-", "_"))), "true")
+	}
 	// Build with cargo-zigbuild
 	container = container.
-		WithExec([]string{"cargo", "zigbuild", "--release", "--target", target})
+		WithExec(args)
 	
 	// Get the binary path
-	binaryPath := fmt.Sprintf("/src/target/%s/release/just-mcp", target)
+	binaryName := "just-mcp"
+	if isWindowsTarget(target) {
+		binaryName = "just-mcp.exe"
+	}
+	binaryPath := fmt.Sprintf("%s/target/%s/%s/%s", m.workdir(), target, profileDir(profile), binaryName)
 	
 	// Extract the binary from the built container
 	binary := container.File(binaryPath)
+
+	if isWindowsTarget(target) {
+		if err := verifyWindowsBinary(ctx, binary, target); err != nil {
+			return nil, err
+		}
+	}
 	
-	// Create archive with binary, README, and LICENSE
-	archiveName := fmt.Sprintf("just-mcp-%s-%s", version, target)
-	
+	// Create archive with binary and documentation files
+	// Windows users get a .zip, everyone else a .tar.gz
+	archiveName := fmt.Sprintf("just-mcp-%s-%s%s", label, target, archiveExtension(target))
+	archiveCmd := []string{"tar", "czf", "/" + archiveName, "."}
+	extractCmd := []string{"tar", "xzf", "/" + archiveName, "-C", "/extract"}
+	if isWindowsTarget(target) {
+		archiveCmd = []string{"zip", "-r", "/" + archiveName, "."}
+		extractCmd = []string{"unzip", "/" + archiveName, "-d", "/extract"}
+	}
+
 	archiveContainer := dag.Container().
-		From("alpine:latest").
-		WithExec([]string{"apk", "add", "--no-cache", "tar", "gzip"}).
-		WithDirectory("/archive", dag.Directory().
-			WithFile("just-mcp", binary).
-			WithFile("README.md", source.File("README.md")).
-			WithFile("LICENSE", source.File("LICENSE")))
+		From(archiveImage).
+		WithExec([]string{"apk", "add", "--no-cache", "tar", "gzip", "zip", "unzip"}).
+		WithDirectory("/archive", collectDocFiles(ctx, source, docFiles).
+			WithFile(binaryName, binary))
 	
 	archive := archiveContainer.
 		WithWorkdir("/archive").
-		WithExec([]string{"tar", "czf", fmt.Sprintf("/%s.tar.gz", archiveName), "."}).
-		File(fmt.Sprintf("/%s.tar.gz", archiveName))
-	
-	return archive, nil
+		WithExec(archiveCmd).
+		File("/" + archiveName)
+
+	// Verify the binary survived the hand-off between containers intact
+	expected, err := sha256Of(ctx, binary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s binary: %w", target, err)
+	}
+	extracted := archiveContainer.
+		WithFile("/"+archiveName, archive).
+		WithExec([]string{"mkdir", "-p", "/extract"}).
+		WithExec(extractCmd).
+		File("/extract/" + binaryName)
+	actual, err := sha256Of(ctx, extracted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash archived %s binary: %w", target, err)
+	}
+	if actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: built %s, archived %s", target, expected, actual)
+	}
+	fmt.Printf("🔐 Verified %s binary sha256 %s\n", target, expected)
+
+	return &ZigbuildArtifact{
+		Target:       target,
+		ArchiveName:  archiveName,
+		Archive:      archive,
+		BinarySha256: expected,
+	}, nil
+}
+
+// profileDir returns the directory under target/<triple> cargo writes a
+// profile's artifacts to; the built-in profiles share debug and release
+func profileDir(profile string) string {
+	switch profile {
+	case "dev", "test":
+		return "debug"
+	case "bench":
+		return "release"
+	}
+	return profile
+}
+
+// releaseConfig is the per-target release matrix read from a JSON build
+// config, e.g.
+//
+//	{"targets": {"x86_64-unknown-linux-gnu": {"features": "systemd", "strip": true, "profile": "dist"}}}
+//
+// Targets left out build with the defaults
+type releaseConfig struct {
+	Targets map[string]releaseTarget `json:"targets"`
+}
+
+// releaseTarget is the build configuration for one release target
+type releaseTarget struct {
+	// Comma-separated cargo features to enable on top of the defaults
+	Features string `json:"features"`
+	// Strip symbols from the binary
+	Strip bool `json:"strip"`
+	// Cargo profile to build with; defaults to release
+	Profile string `json:"profile"`
+}
+
+// parseReleaseConfig reads a build config, rejecting unknown fields and
+// targets so typos don't silently fall back to the defaults
+func parseReleaseConfig(ctx context.Context, file *dagger.File) (releaseConfig, error) {
+	var config releaseConfig
+	if file == nil {
+		return config, nil
+	}
+	contents, err := file.Contents(ctx)
+	if err != nil {
+		return config, fmt.Errorf("failed to read build config: %w", err)
+	}
+	decoder := json.NewDecoder(strings.NewReader(contents))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("invalid build config: %w", err)
+	}
+	for target := range config.Targets {
+		if !slices.Contains(zigbuildTargets, target) {
+			return config, fmt.Errorf("invalid build config: unknown target %q", target)
+		}
+	}
+	return config, nil
+}
+
+// zigbuildTargets are the target triples ReleaseZigbuild ships archives for
+var zigbuildTargets = []string{
+	"x86_64-unknown-linux-gnu",
+	"aarch64-unknown-linux-gnu",
+	"x86_64-unknown-linux-musl",
+	"aarch64-unknown-linux-musl",
+	"x86_64-apple-darwin",
+	"aarch64-apple-darwin",
+	"universal2-apple-darwin",
+	"aarch64-pc-windows-gnullvm",
 }
 
 // ReleaseZigbuild builds releases for all platforms using cargo-zigbuild
@@ -358,18 +2194,40 @@ func (m *JustMcp) ReleaseZigbuild(
 	// +optional
 	// +default="v0.1.0"
 	version string,
+	// Extra features per target as "<triple>=<features>" entries, e.g.
+	// "x86_64-unknown-linux-gnu=systemd"; other targets use the defaults
+	// These override features from buildConfig
+	// +optional
+	targetFeatures []string,
+	// JSON build config with per-target settings:
+	// {"targets": {"<triple>": {"features": "...", "strip": true, "profile": "release"}}}
+	// +optional
+	buildConfig *dagger.File,
 ) (*dagger.Directory, error) {
-	platforms := []string{
-		"x86_64-unknown-linux-gnu",
-		"aarch64-unknown-linux-gnu",
-		"x86_64-apple-darwin",
-		"aarch64-apple-darwin",
-		"universal2-apple-darwin",
+	platforms := zigbuildTargets
+
+	config, err := parseReleaseConfig(ctx, buildConfig)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]releaseTarget{}
+	for target, setting := range config.Targets {
+		settings[target] = setting
+	}
+	for _, entry := range targetFeatures {
+		target, list, ok := strings.Cut(entry, "=")
+		if !ok || !slices.Contains(platforms, target) {
+			return nil, fmt.Errorf("invalid target features %q: expected <triple>=<features> for a known target", entry)
+		}
+		setting := settings[target]
+		setting.Features = list
+		settings[target] = setting
 	}
 	
 	// Use goroutines to build all platforms in parallel
 	type result struct {
 		target  string
+		name    string
 		archive *dagger.File
 		err     error
 	}
@@ -382,8 +2240,13 @@ func (m *JustMcp) ReleaseZigbuild(
 		wg.Add(1)
 		go func(t string) {
 			defer wg.Done()
-			archive, err := m.ZigbuildSingle(ctx, source, t, version)
-			results <- result{target: t, archive: archive, err: err}
+			setting := settings[t]
+			artifact, err := m.ZigbuildSingle(ctx, source, t, version, nil, false, "", "", setting.Features, setting.Profile, setting.Strip)
+			if err != nil {
+				results <- result{target: t, err: err}
+				return
+			}
+			results <- result{target: t, name: artifact.ArchiveName, archive: artifact.Archive}
 		}(target)
 	}
 	
@@ -402,8 +2265,7 @@ func (m *JustMcp) ReleaseZigbuild(
 			errors = append(errors, fmt.Sprintf("%s: %v", res.target, res.err))
 		} else {
 			// Add each archive to the directory with its proper filename
-			archiveName := fmt.Sprintf("just-mcp-%s-%s.tar.gz", version, res.target)
-			releaseDir = releaseDir.WithFile(archiveName, res.archive)
+			releaseDir = releaseDir.WithFile(res.name, res.archive)
 		}
 	}
 	
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"dagger/just-mcp/internal/dagger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// criterionEstimates is the subset of Criterion's estimates.json we care about
+type criterionEstimates struct {
+	Mean struct {
+		PointEstimate float64 `json:"point_estimate"`
+	} `json:"mean"`
+}
+
+// BenchCompare runs the Criterion benchmarks on baseRef and on the current
+// source and reports the change in mean time per benchmark, failing when any
+// benchmark regresses by more than threshold percent. Source must include .git
+func (m *JustMcp) BenchCompare(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Git ref to compare against, e.g. origin/main
+	baseRef string,
+	// Maximum allowed slowdown in percent
+	// +optional
+	// +default=10
+	threshold float64,
+	// Criterion bench target to run; the lib and bin targets use the libtest
+	// harness, which rejects Criterion's --save-baseline
+	// +optional
+	// +default="ast_parser_bench"
+	bench string,
+) (string, error) {
+	if bench == "" {
+		bench = "ast_parser_bench"
+	}
+
+	container, err := withGit(ctx, m.rustContainer(source), source)
+	if err != nil {
+		return "", err
+	}
+
+	// Both runs share a target dir so Criterion keeps both baselines side by side
+	criterion := container.
+		WithEnvVariable("CARGO_TARGET_DIR", "/tmp/bench-target").
+		WithExec([]string{"git", "worktree", "add", "--detach", "/base", baseRef}).
+		WithWorkdir(path.Join("/base", m.Subdir)).
+		WithExec([]string{"cargo", "bench", "--bench", bench, "--", "--save-baseline", "base"}).
+		WithWorkdir(m.workdir()).
+		WithExec([]string{"cargo", "bench", "--bench", bench, "--", "--save-baseline", "head"}).
+		Directory("/tmp/bench-target/criterion")
+
+	heads, err := criterion.Glob(ctx, "**/head/estimates.json")
+	if err != nil {
+		return "", err
+	}
+	if len(heads) == 0 {
+		return "", fmt.Errorf("%s produced no Criterion estimates", bench)
+	}
+	sort.Strings(heads)
+
+	var report, regressions []string
+	for _, head := range heads {
+		benchmark := strings.TrimSuffix(head, "/head/estimates.json")
+
+		headMean, err := criterionMean(ctx, criterion.File(head))
+		if err != nil {
+			return "", err
+		}
+		baseMean, err := criterionMean(ctx, criterion.File(benchmark+"/base/estimates.json"))
+		if err != nil {
+			// Benchmark doesn't exist on the base ref
+			report = append(report, fmt.Sprintf("%-60s new", benchmark))
+			continue
+		}
+
+		change := (headMean - baseMean) / baseMean * 100
+		line := fmt.Sprintf("%-60s %+7.2f%%", benchmark, change)
+		report = append(report, line)
+		if change > threshold {
+			regressions = append(regressions, line)
+		}
+	}
+
+	if len(regressions) > 0 {
+		return "", fmt.Errorf("benchmarks regressed by more than %.1f%%:\n%s", threshold, strings.Join(regressions, "\n"))
+	}
+	return strings.Join(report, "\n"), nil
+}
+
+// criterionMean reads the mean point estimate (in ns) from an estimates.json
+func criterionMean(ctx context.Context, file *dagger.File) (float64, error) {
+	contents, err := file.Contents(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var estimates criterionEstimates
+	if err := json.Unmarshal([]byte(contents), &estimates); err != nil {
+		return 0, fmt.Errorf("failed to parse criterion estimates: %w", err)
+	}
+	return estimates.Mean.PointEstimate, nil
+}
+
+// perfProbeScript starts the server behind a pair of FIFOs so each response
+// can be timed as it arrives, then prints the milliseconds until the
+// initialize and tools/list responses
+const perfProbeScript = `set -e
+mkfifo /tmp/in /tmp/out
+start=$(date +%s%N)
+just-mcp --watch-dir /fixtures < /tmp/in > /tmp/out 2>/dev/null &
+exec 3>/tmp/in 4</tmp/out
+
+cat /tmp/initialize.jsonl >&3
+while read -r line <&4; do case "$line" in *'"id":1,'*|*'"id":1}'*) break ;; esac; done
+initialized=$(date +%s%N)
+
+cat /tmp/list.jsonl >&3
+while read -r line <&4; do case "$line" in *'"id":2,'*|*'"id":2}'*) break ;; esac; done
+listed=$(date +%s%N)
+
+exec 3>&-
+wait
+echo $(( (initialized - start) / 1000000 )) $(( (listed - initialized) / 1000000 ))
+`
+
+// PerfProbe measures how quickly the release binary answers initialize (from
+// process start) and tools/list against a justfile with many recipes, failing
+// when either exceeds its threshold
+func (m *JustMcp) PerfProbe(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Maximum milliseconds from start until the initialize response
+	// +optional
+	// +default=500
+	maxInitializeMs int,
+	// Maximum milliseconds for the tools/list response
+	// +optional
+	// +default=500
+	maxListMs int,
+	// Number of recipes in the generated fixture justfile
+	// +optional
+	// +default=200
+	recipes int,
+) (string, error) {
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+
+	var justfile strings.Builder
+	for i := range recipes {
+		fmt.Fprintf(&justfile, "# Recipe number %d\nrecipe-%d target=\"all\" *flags:\n    echo \"%d {{target}} {{flags}}\"\n\n", i, i, i)
+	}
+	fixtures := dag.Directory().WithNewFile("justfile", justfile.String())
+
+	initialize, list, _ := strings.Cut(mcpListToolsSession, "\n")
+	out, err := runtimeContainer(binary, fixtures).
+		WithNewFile("/tmp/initialize.jsonl", initialize+"\n").
+		WithNewFile("/tmp/list.jsonl", list).
+		WithExec([]string{"bash", "-c", perfProbeScript}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("perf probe failed: %w", err)
+	}
+
+	var initializeMs, listMs int
+	if _, err := fmt.Sscan(out, &initializeMs, &listMs); err != nil {
+		return "", fmt.Errorf("unexpected perf probe output %q: %w", out, err)
+	}
+
+	report := fmt.Sprintf("initialize: %dms (max %dms)\ntools/list: %dms (max %dms) with %d recipes",
+		initializeMs, maxInitializeMs, listMs, maxListMs, recipes)
+	if initializeMs > maxInitializeMs || listMs > maxListMs {
+		return "", fmt.Errorf("server latency over threshold:\n%s", report)
+	}
+	return "⏱️  " + report, nil
+}
+
+// loadTestScript starts $CONCURRENCY server processes at once, each behind a
+// FIFO like perfProbeScript, and records the wall time of the whole run. A
+// session's stdin stays open until all its responses have arrived or $TIMEOUT
+// seconds have passed, so in-flight requests aren't cut off by EOF. At the
+// deadline the server is killed and its missing responses count as timeouts
+const loadTestScript = `mkdir -p /tmp/load
+session() {
+  mkfifo "/tmp/in-$1"
+  just-mcp --watch-dir /fixtures < "/tmp/in-$1" > "/tmp/load/$1.jsonl" 2>/dev/null &
+  server=$!
+  exec 3>"/tmp/in-$1"
+  cat "/tmp/sessions/$1.jsonl" >&3
+  expected=$(grep -c '"id":' "/tmp/sessions/$1.jsonl")
+  deadline=$(( $(date +%s) + TIMEOUT ))
+  while [ "$(grep -c '"id":' "/tmp/load/$1.jsonl")" -lt "$expected" ] && [ "$(date +%s)" -lt "$deadline" ]; do
+    sleep 0.05
+  done
+  exec 3>&-
+  kill "$server" 2>/dev/null
+  wait "$server" 2>/dev/null
+}
+start=$(date +%s%N)
+for i in $(seq "$CONCURRENCY"); do
+  session "$i" &
+done
+wait
+end=$(date +%s%N)
+echo $(( (end - start) / 1000000 )) > /tmp/load/elapsed-ms
+`
+
+// fixtureRecipe matches a recipe header line in a justfile
+var fixtureRecipe = regexp.MustCompile(`(?m)^([A-Za-z_][A-Za-z0-9_-]*)[^:\n]*:`)
+
+// loadTestTool lists the server's tools for the fixture justfile and returns
+// the first one that runs a fixture recipe, so LoadTest calls a tool that
+// exists rather than a hard-coded name
+func loadTestTool(ctx context.Context, container *dagger.Container) (string, error) {
+	out, err := container.
+		WithExec([]string{"just-mcp", "--watch-dir", "/fixtures"}, dagger.ContainerWithExecOpts{
+			Stdin: mcpListToolsSession,
+		}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("MCP session failed: %w", err)
+	}
+	result, err := mcpResult(out, 2)
+	if err != nil {
+		return "", err
+	}
+	var listing struct {
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(result, &listing); err != nil {
+		return "", fmt.Errorf("failed to parse tools/list result: %w", err)
+	}
+
+	for _, recipe := range fixtureRecipe.FindAllStringSubmatch(fixtureJustfile, -1) {
+		for _, tool := range listing.Tools {
+			if tool.Name == recipe[1] {
+				return tool.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("tools/list has no tool for a fixture recipe: %s", result)
+}
+
+// LoadTest drives the release server with requests spread over concurrency
+// simultaneous client sessions (the server speaks stdio, so each session is
+// its own process). Each session writes all of its alternating tools/list and
+// tools/call requests up front, calling the first listed tool backed by a
+// fixture recipe, then keeps stdin open until every response has arrived or
+// the timeout passes, exercising concurrent request handling. Reports
+// throughput and fails when the share of error responses and timeouts
+// exceeds maxErrorPercent
+func (m *JustMcp) LoadTest(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Number of simultaneous client sessions
+	// +optional
+	// +default=8
+	concurrency int,
+	// Total number of requests across all sessions
+	// +optional
+	// +default=400
+	requests int,
+	// Maximum percentage of failed or unanswered requests
+	// +optional
+	// +default=1
+	maxErrorPercent float64,
+	// Seconds before a session is killed and its outstanding requests count
+	// as timeouts
+	// +optional
+	// +default=60
+	timeout int,
+) (string, error) {
+	if concurrency <= 0 || requests < concurrency {
+		return "", fmt.Errorf("need at least one request per session, got %d requests for %d sessions", requests, concurrency)
+	}
+	if timeout <= 0 {
+		timeout = 60
+	}
+
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+	container := runtimeContainer(binary, nil).With(m.withJust("linux/amd64"))
+	tool, err := loadTestTool(ctx, container)
+	if err != nil {
+		return "", err
+	}
+
+	initialize, _, _ := strings.Cut(mcpListToolsSession, "\n")
+	sessions := dag.Directory()
+	perSession := make([]int, concurrency)
+	for i := range requests {
+		perSession[i%concurrency]++
+	}
+	for i, count := range perSession {
+		var session strings.Builder
+		session.WriteString(initialize + "\n")
+		session.WriteString(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n")
+		// Request ids start at 2, after initialize
+		for id := 2; id < count+2; id++ {
+			if id%2 == 0 {
+				fmt.Fprintf(&session, `{"jsonrpc":"2.0","id":%d,"method":"tools/list","params":{}}`+"\n", id)
+			} else {
+				fmt.Fprintf(&session, `{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q,"arguments":{}}}`+"\n", id, tool)
+			}
+		}
+		sessions = sessions.WithNewFile(fmt.Sprintf("%d.jsonl", i+1), session.String())
+	}
+
+	fmt.Printf("🧪 Sending %d requests over %d sessions, calling %s...\n", requests, concurrency, tool)
+	results := container.
+		WithDirectory("/tmp/sessions", sessions).
+		WithEnvVariable("CONCURRENCY", strconv.Itoa(concurrency)).
+		WithEnvVariable("TIMEOUT", strconv.Itoa(timeout)).
+		WithExec([]string{"bash", "-c", loadTestScript}).
+		Directory("/tmp/load")
+
+	elapsedOut, err := results.File("elapsed-ms").Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("load test failed: %w", err)
+	}
+	elapsedMs, err := strconv.Atoi(strings.TrimSpace(elapsedOut))
+	if err != nil {
+		return "", fmt.Errorf("unexpected load test timing %q: %w", elapsedOut, err)
+	}
+
+	var errorCount, timeouts int
+	var samples []string
+	for i, count := range perSession {
+		transcript, err := results.File(fmt.Sprintf("%d.jsonl", i+1)).Contents(ctx)
+		if err != nil {
+			return "", err
+		}
+		for id := 2; id < count+2; id++ {
+			result, err := mcpResult(transcript, id)
+			var failure string
+			switch {
+			case errors.As(err, new(*mcpError)):
+				errorCount++
+				failure = err.Error()
+			case err != nil:
+				timeouts++
+				continue
+			case id%2 == 1:
+				var call struct {
+					IsError bool `json:"isError"`
+				}
+				if json.Unmarshal(result, &call) != nil || call.IsError {
+					errorCount++
+					failure = fmt.Sprintf("tools/call %d failed: %s", id, result)
+				}
+			}
+			if failure != "" && len(samples) < 5 {
+				samples = append(samples, fmt.Sprintf("session %d: %s", i+1, failure))
+			}
+		}
+	}
+
+	failedPercent := float64(errorCount+timeouts) * 100 / float64(requests)
+	report := fmt.Sprintf("%d requests over %d sessions in %dms (%.1f req/s)\nerrors: %d, timeouts: %d (%.2f%%, max %.2f%%)",
+		requests, concurrency, elapsedMs, float64(requests)*1000/float64(max(elapsedMs, 1)),
+		errorCount, timeouts, failedPercent, maxErrorPercent)
+	if len(samples) > 0 {
+		report += "\n" + strings.Join(samples, "\n")
+	}
+	if failedPercent > maxErrorPercent {
+		return "", fmt.Errorf("error rate over threshold:\n%s", report)
+	}
+	return "📊 " + report, nil
+}
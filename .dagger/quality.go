@@ -0,0 +1,792 @@
+package main
+
+import (
+	"context"
+	"dagger/just-mcp/internal/dagger"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StageResult captures the outcome of a single pipeline stage
+type StageResult struct {
+	// Name of the stage
+	Name string
+	// Whether the stage succeeded
+	Passed bool
+	// Combined stdout and stderr of the stage
+	Output string
+	// Wall-clock duration of the stage (e.g. "1m2.5s")
+	Duration string
+}
+
+// QualityReport aggregates the results of the static-analysis stages
+type QualityReport struct {
+	// Whether every stage succeeded
+	Passed bool
+	// Individual stage results, in execution order
+	Stages []StageResult
+}
+
+// QualityReport runs the static-only quality gate (format, clippy, audit)
+// Every stage runs even if an earlier one fails; unlike CI, no tests are run
+func (m *JustMcp) QualityReport(ctx context.Context, source *dagger.Directory) (*QualityReport, error) {
+	container := m.buildContainer(source)
+	auditContainer := container.
+		WithExec([]string{"cargo", "install", "cargo-audit", "--locked"}).
+		// cargo-audit needs a lockfile, which isn't committed for this crate
+		WithExec([]string{"sh", "-c", "test -f Cargo.lock || cargo generate-lockfile"})
+
+	stages := []struct {
+		name      string
+		container *dagger.Container
+		args      []string
+	}{
+		{"format", container, []string{"cargo", "fmt", "--", "--check"}},
+		{"clippy", withCargoCache(container, "clippy", m.workdir()+"/target/clippy"), []string{"cargo", "clippy", "--", "-D", "warnings"}},
+		{"audit", auditContainer, []string{"cargo", "audit"}},
+	}
+
+	report := &QualityReport{Passed: true}
+	for _, stage := range stages {
+		fmt.Printf("🔎 Running %s...\n", stage.name)
+		result := runStage(ctx, stage.container, stage.name, stage.args)
+		if !result.Passed {
+			report.Passed = false
+		}
+		report.Stages = append(report.Stages, result)
+	}
+
+	return report, nil
+}
+
+// runStage executes a command without failing the pipeline on a non-zero exit
+// code, capturing its output and duration into a StageResult
+func runStage(ctx context.Context, container *dagger.Container, name string, args []string) StageResult {
+	start := time.Now()
+	passed, output := execCapture(ctx, container, args)
+	return StageResult{
+		Name:     name,
+		Passed:   passed,
+		Output:   output,
+		Duration: time.Since(start).Round(time.Millisecond).String(),
+	}
+}
+
+// execCapture runs args in the container, returning whether it exited
+// successfully along with its combined stdout and stderr
+func execCapture(ctx context.Context, container *dagger.Container, args []string) (bool, string) {
+	executed := container.WithExec(args, dagger.ContainerWithExecOpts{
+		Expect: dagger.ReturnTypeAny,
+	})
+
+	exitCode, err := executed.ExitCode(ctx)
+	if err != nil {
+		return false, err.Error()
+	}
+	stdout, err := executed.Stdout(ctx)
+	if err != nil {
+		return false, err.Error()
+	}
+	stderr, err := executed.Stderr(ctx)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	return exitCode == 0, stdout + stderr
+}
+
+// PreCommit runs the format and clippy checks in the CI container and, when
+// either fails, returns the formatting diff and clippy messages in one report
+func (m *JustMcp) PreCommit(ctx context.Context, source *dagger.Directory) (string, error) {
+	container := m.buildContainer(source)
+
+	fmt.Println("🔍 Checking code formatting...")
+	fmtPassed, fmtOut := execCapture(ctx, container, []string{"cargo", "fmt", "--", "--check"})
+	fmt.Println("📋 Running clippy linter...")
+	clippyPassed, clippyOut := execCapture(ctx,
+		withCargoCache(container, "clippy", m.workdir()+"/target/clippy"),
+		[]string{"cargo", "clippy", "--", "-D", "warnings"})
+
+	if fmtPassed && clippyPassed {
+		return "✅ Code is formatted and clippy-clean", nil
+	}
+
+	var report strings.Builder
+	if !fmtPassed {
+		fmt.Fprintf(&report, "=== cargo fmt ===\n%s\n", fmtOut)
+	}
+	if !clippyPassed {
+		fmt.Fprintf(&report, "=== cargo clippy ===\n%s\n", clippyOut)
+	}
+	return "", fmt.Errorf("pre-commit checks failed:\n%s", report.String())
+}
+
+// CliParity checks that debug and release binaries expose the same CLI surface
+// Returns the diff of their --help output as an error when they differ
+func (m *JustMcp) CliParity(ctx context.Context, source *dagger.Directory) (string, error) {
+	debug := m.buildBinary(source, "linux/amd64", buildOptions{})
+	release := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+
+	container := dag.Container().
+		From("rust:1.88.0").
+		WithFile("/debug/just-mcp", debug).
+		WithFile("/release/just-mcp", release).
+		WithExec([]string{"/debug/just-mcp", "--help"}, dagger.ContainerWithExecOpts{
+			RedirectStdout: "/debug.txt",
+		}).
+		WithExec([]string{"/release/just-mcp", "--help"}, dagger.ContainerWithExecOpts{
+			RedirectStdout: "/release.txt",
+		})
+
+	passed, diff := execCapture(ctx, container, []string{"diff", "-u", "/debug.txt", "/release.txt"})
+	if !passed {
+		return "", fmt.Errorf("debug and release CLI surfaces differ:\n%s", diff)
+	}
+
+	return "✅ Debug and release binaries expose the same CLI", nil
+}
+
+// clippyMessage is the subset of cargo's JSON diagnostic output we care about
+type clippyMessage struct {
+	Reason  string `json:"reason"`
+	Message struct {
+		Level   string `json:"level"`
+		Message string `json:"message"`
+		Code    *struct {
+			Code string `json:"code"`
+		} `json:"code"`
+		Spans []struct {
+			FileName  string `json:"file_name"`
+			IsPrimary bool   `json:"is_primary"`
+		} `json:"spans"`
+	} `json:"message"`
+}
+
+// LintBaseline runs clippy and fails only on warnings missing from the baseline
+// Baseline entries are "<lint>\t<file>\t<message>" lines, deliberately without
+// line numbers so unrelated edits don't invalidate them. Returns the updated
+// baseline; without a baseline, the current warnings become the new baseline
+func (m *JustMcp) LintBaseline(
+	ctx context.Context,
+	source *dagger.Directory,
+	// +optional
+	baseline *dagger.File,
+) (*dagger.File, error) {
+	out, err := withCargoCache(m.buildContainer(source), "clippy", m.workdir()+"/target/clippy").
+		WithExec([]string{"cargo", "clippy", "--message-format=json"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := clippyDiagnostics(out)
+	if err != nil {
+		return nil, err
+	}
+	updated := dag.Directory().
+		WithNewFile("clippy-baseline.txt", strings.Join(current, "\n")+"\n").
+		File("clippy-baseline.txt")
+
+	if baseline == nil {
+		fmt.Printf("📋 No baseline provided, recording %d warning(s)\n", len(current))
+		return updated, nil
+	}
+
+	contents, err := baseline.Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	known := map[string]bool{}
+	for _, line := range strings.Split(contents, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			known[line] = true
+		}
+	}
+
+	var added []string
+	for _, d := range current {
+		if !known[d] {
+			added = append(added, d)
+		}
+	}
+	if len(added) > 0 {
+		return nil, fmt.Errorf("%d new clippy warning(s) not in baseline:\n%s", len(added), strings.Join(added, "\n"))
+	}
+
+	fmt.Printf("✅ No new clippy warnings (%d in baseline, %d remaining)\n", len(known), len(current))
+	return updated, nil
+}
+
+// clippyDiagnostics extracts sorted, de-duplicated baseline keys from cargo's
+// JSON message stream
+func clippyDiagnostics(stream string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, line := range strings.Split(stream, "\n") {
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var msg clippyMessage
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			return nil, fmt.Errorf("failed to parse clippy output: %w", err)
+		}
+		if msg.Reason != "compiler-message" || msg.Message.Code == nil {
+			continue
+		}
+
+		file := ""
+		for _, span := range msg.Message.Spans {
+			if span.IsPrimary {
+				file = span.FileName
+				break
+			}
+		}
+		seen[fmt.Sprintf("%s\t%s\t%s", msg.Message.Code.Code, file, msg.Message.Message)] = true
+	}
+
+	diagnostics := make([]string, 0, len(seen))
+	for d := range seen {
+		diagnostics = append(diagnostics, d)
+	}
+	sort.Strings(diagnostics)
+	return diagnostics, nil
+}
+
+// udepsReport is the subset of cargo-udeps' JSON output we care about
+type udepsReport struct {
+	UnusedDeps map[string]struct {
+		Normal      []string `json:"normal"`
+		Development []string `json:"development"`
+		Build       []string `json:"build"`
+	} `json:"unused_deps"`
+}
+
+// Udeps reports unused dependencies using cargo-udeps (requires nightly)
+// Fails when unused dependencies are found that aren't listed in allowUnused
+func (m *JustMcp) Udeps(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Dependency names to ignore (known false positives)
+	// +optional
+	allowUnused []string,
+) (string, error) {
+	container := m.nightlyContainer(source).
+		WithExec([]string{"cargo", "install", "cargo-udeps", "--locked"}).
+		// cargo-udeps exits non-zero when it finds unused dependencies
+		WithExec([]string{"cargo", "+nightly", "udeps", "--all-targets", "--output", "json"}, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		})
+
+	out, err := container.Stdout(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var report udepsReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		stderr, _ := container.Stderr(ctx)
+		return "", fmt.Errorf("failed to parse cargo-udeps output: %w\n%s", err, stderr)
+	}
+
+	var unused []string
+	for pkg, deps := range report.UnusedDeps {
+		kinds := []struct {
+			kind string
+			deps []string
+		}{
+			{"normal", deps.Normal},
+			{"dev", deps.Development},
+			{"build", deps.Build},
+		}
+		for _, k := range kinds {
+			for _, dep := range k.deps {
+				if !slices.Contains(allowUnused, dep) {
+					unused = append(unused, fmt.Sprintf("%s: %s (%s)", pkg, dep, k.kind))
+				}
+			}
+		}
+	}
+	sort.Strings(unused)
+
+	if len(unused) > 0 {
+		return "", fmt.Errorf("unused dependencies found:\n%s", strings.Join(unused, "\n"))
+	}
+	return "✅ No unused dependencies", nil
+}
+
+// Machete reports unused dependencies using cargo-machete, a fast, stable
+// alternative to Udeps that scans sources instead of compiling them
+func (m *JustMcp) Machete(ctx context.Context, source *dagger.Directory) (string, error) {
+	container := m.buildContainer(source).
+		WithExec([]string{"cargo", "install", "cargo-machete", "--locked"})
+
+	// cargo-machete exits non-zero when it finds unused dependencies
+	passed, out := execCapture(ctx, container, []string{"cargo", "machete"})
+	if !passed {
+		return "", fmt.Errorf("unused dependencies found:\n%s", out)
+	}
+	return "✅ No unused dependencies\n" + out, nil
+}
+
+// MacheteFix removes the dependencies cargo-machete reports as unused and
+// returns every Cargo.toml of the workspace (e.g. dev-tools/Cargo.toml too)
+// at its path relative to the crate root, ready to be exported over it
+func (m *JustMcp) MacheteFix(ctx context.Context, source *dagger.Directory) (*dagger.Directory, error) {
+	fixed := m.buildContainer(source).
+		WithExec([]string{"cargo", "install", "cargo-machete", "--locked"}).
+		// --fix still exits non-zero when it had something to remove
+		WithExec([]string{"cargo", "machete", "--fix"}, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		}).
+		Directory(m.workdir())
+
+	return dag.Directory().WithDirectory(".", fixed, dagger.DirectoryWithDirectoryOpts{
+		Include: []string{"**/Cargo.toml"},
+		Exclude: []string{"target"},
+	}), nil
+}
+
+// GrepCheck fails if any of the given regular expressions match in src/
+// With excludeTests, everything from a file's first #[cfg(test)] onwards is
+// ignored, matching the convention of keeping unit tests at the end of a module
+func (m *JustMcp) GrepCheck(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Forbidden patterns, e.g. "dbg!" or "\.unwrap\(\)"
+	patterns []string,
+	// Skip #[cfg(test)] modules
+	// +optional
+	excludeTests bool,
+) (string, error) {
+	var regexps []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		regexps = append(regexps, re)
+	}
+
+	var offences []string
+	sources := path.Join(m.Subdir, "src/**/*.rs")
+	files, err := forEachSourceLine(ctx, source, []string{sources}, func(file string, n int, line string) bool {
+		if excludeTests && strings.TrimSpace(line) == "#[cfg(test)]" {
+			return false
+		}
+		for _, re := range regexps {
+			if re.MatchString(line) {
+				offences = append(offences, fmt.Sprintf("%s:%d: %s (matches %q)", file, n, strings.TrimSpace(line), re.String()))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	if files == 0 {
+		return "", fmt.Errorf("no Rust sources match %s", sources)
+	}
+
+	if len(offences) > 0 {
+		return "", fmt.Errorf("found %d forbidden pattern match(es):\n%s", len(offences), strings.Join(offences, "\n"))
+	}
+	return fmt.Sprintf("✅ No forbidden patterns in %d files", files), nil
+}
+
+// TodoCheck reports TODO/FIXME comments that don't reference an issue (#123)
+// in the Rust sources and this pipeline's own Go code
+func (m *JustMcp) TodoCheck(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Regular expression identifying a TODO-style comment
+	// +optional
+	// +default="\\b(TODO|FIXME)\\b"
+	pattern string,
+	// Fail when unreferenced TODOs are found instead of just reporting them
+	// +optional
+	fail bool,
+) (string, error) {
+	if pattern == "" {
+		pattern = `\b(TODO|FIXME)\b`
+	}
+	marker, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	issueRef := regexp.MustCompile(`#\d+`)
+
+	var todos []string
+	// The crate may live in a subdirectory; the pipeline itself is at the root
+	globs := []string{
+		path.Join(m.Subdir, "src/**/*.rs"),
+		path.Join(m.Subdir, "tests/**/*.rs"),
+		path.Join(m.Subdir, "benches/**/*.rs"),
+		".dagger/*.go",
+	}
+	files, err := forEachSourceLine(ctx, source, globs, func(file string, n int, line string) bool {
+		if marker.MatchString(line) && !issueRef.MatchString(line) {
+			todos = append(todos, fmt.Sprintf("%s:%d: %s", file, n, strings.TrimSpace(line)))
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(todos) == 0 {
+		return fmt.Sprintf("✅ All TODOs in %d files reference an issue", files), nil
+	}
+
+	report := fmt.Sprintf("found %d TODO(s) without an issue reference:\n%s", len(todos), strings.Join(todos, "\n"))
+	if fail {
+		return "", fmt.Errorf("%s", report)
+	}
+	return "⚠️  " + report, nil
+}
+
+// SpellCheck checks doc comments for spelling mistakes using cargo-spellcheck
+func (m *JustMcp) SpellCheck(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Hunspell .dic file in source with project-specific words, added to the
+	// builtin English dictionary
+	// +optional
+	dictionary string,
+	// Fail when misspellings are found instead of just reporting them
+	// +optional
+	fail bool,
+) (string, error) {
+	container := m.buildContainer(source).
+		// hunspell bindings are generated with bindgen, which needs libclang
+		WithExec([]string{"sh", "-c", "apt-get update && apt-get install -y libclang-dev"}).
+		WithExec([]string{"cargo", "install", "cargo-spellcheck", "--locked"})
+
+	// --code 1 makes cargo-spellcheck exit non-zero when it finds mistakes
+	args := []string{"cargo", "spellcheck", "check", "--code", "1"}
+	if dictionary != "" {
+		entries, err := source.Entries(ctx, dagger.DirectoryEntriesOpts{Path: path.Dir(dictionary)})
+		if err != nil || !slices.Contains(entries, path.Base(dictionary)) {
+			return "", fmt.Errorf("dictionary %s not found in source", dictionary)
+		}
+
+		dicPath := path.Join("/src", dictionary)
+		config := fmt.Sprintf("[Hunspell]\nuse_builtin = true\nsearch_dirs = [%q]\nextra_dictionaries = [%q]\n",
+			path.Dir(dicPath), path.Base(dicPath))
+		container = container.WithNewFile("/tmp/spellcheck.toml", config)
+		args = append(args, "--cfg", "/tmp/spellcheck.toml")
+	}
+
+	fmt.Println("📝 Spell checking doc comments...")
+	passed, out := execCapture(ctx, container, args)
+	if passed {
+		return "✅ No spelling mistakes found", nil
+	}
+	if fail {
+		return "", fmt.Errorf("spelling mistakes found:\n%s", out)
+	}
+	return "⚠️  Spelling mistakes found:\n" + out, nil
+}
+
+// forEachSourceLine calls fn for every line of the files in source matching
+// globs; fn returns false to skip the rest of the current file. Returns the
+// number of files scanned
+func forEachSourceLine(ctx context.Context, source *dagger.Directory, globs []string, fn func(file string, n int, line string) bool) (int, error) {
+	var files []string
+	for _, glob := range globs {
+		matches, err := source.Glob(ctx, glob)
+		if err != nil {
+			return 0, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		contents, err := source.File(file).Contents(ctx)
+		if err != nil {
+			return 0, err
+		}
+		for i, line := range strings.Split(contents, "\n") {
+			if !fn(file, i+1, line) {
+				break
+			}
+		}
+	}
+	return len(files), nil
+}
+
+// CheckFeaturePowerset checks that every combination of crate features compiles
+// using cargo-hack. All combinations are attempted and failures reported together
+func (m *JustMcp) CheckFeaturePowerset(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Maximum number of features combined at once (0 = unbounded)
+	// +optional
+	depth int,
+) (string, error) {
+	args := []string{"cargo", "hack", "check", "--feature-powerset", "--no-dev-deps", "--keep-going"}
+	if depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(depth))
+	}
+
+	container := withCargoCache(m.buildContainer(source), "hack", m.workdir()+"/target/hack").
+		WithExec([]string{"cargo", "install", "cargo-hack", "--locked"})
+
+	passed, output := execCapture(ctx, container, args)
+	if !passed {
+		return "", fmt.Errorf("some feature combinations failed to compile:\n%s", output)
+	}
+	return output, nil
+}
+
+// LicenseHeaderCheck verifies every src/**/*.rs file of the crate starts with the header
+// line (e.g. "// SPDX-License-Identifier: MIT"). In fix mode the header is
+// prepended where missing and the modified source directory returned instead
+func (m *JustMcp) LicenseHeaderCheck(
+	ctx context.Context,
+	source *dagger.Directory,
+	header string,
+	// Prepend the header to offending files instead of failing
+	// +optional
+	fix bool,
+) (*dagger.Directory, error) {
+	sources := path.Join(m.Subdir, "src/**/*.rs")
+	files, err := source.Glob(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Rust sources match %s", sources)
+	}
+	sort.Strings(files)
+
+	var missing []string
+	fixed := source
+	for _, file := range files {
+		contents, err := source.File(file).Contents(ctx)
+		if err != nil {
+			return nil, err
+		}
+		firstLine, _, _ := strings.Cut(contents, "\n")
+		if strings.TrimSpace(firstLine) == strings.TrimSpace(header) {
+			continue
+		}
+
+		missing = append(missing, file)
+		fixed = fixed.WithNewFile(file, header+"\n"+contents)
+	}
+
+	if fix {
+		fmt.Printf("📝 Added license header to %d file(s)\n", len(missing))
+		return fixed, nil
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%d file(s) missing license header %q:\n%s", len(missing), header, strings.Join(missing, "\n"))
+	}
+	return source, nil
+}
+
+// PublicApiDiff reports how the crate's public API changed since baseRef
+// cargo-public-api builds rustdoc JSON, which needs nightly; source must
+// include .git
+func (m *JustMcp) PublicApiDiff(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Git ref to compare against, e.g. the last release tag
+	baseRef string,
+) (string, error) {
+	container, err := withGit(ctx, m.nightlyContainer(source), source)
+	if err != nil {
+		return "", err
+	}
+
+	container = container.
+		WithExec([]string{"cargo", "install", "cargo-public-api", "--locked"}).
+		WithExec([]string{"git", "worktree", "add", "--detach", "/base", baseRef}).
+		WithWorkdir(path.Join("/base", m.Subdir)).
+		WithExec([]string{"cargo", "public-api", "--simplified"}, dagger.ContainerWithExecOpts{
+			RedirectStdout: "/tmp/base-api.txt",
+		}).
+		WithWorkdir(m.workdir()).
+		WithExec([]string{"cargo", "public-api", "--simplified"}, dagger.ContainerWithExecOpts{
+			RedirectStdout: "/tmp/head-api.txt",
+		})
+
+	fmt.Printf("🔎 Comparing public API against %s...\n", baseRef)
+	if _, err := container.Sync(ctx); err != nil {
+		return "", fmt.Errorf("failed to compute public API: %w", err)
+	}
+	unchanged, diff := execCapture(ctx, container, []string{"diff", "-u", "/tmp/base-api.txt", "/tmp/head-api.txt"})
+	if unchanged {
+		return fmt.Sprintf("✅ Public API unchanged since %s", baseRef), nil
+	}
+	return diff, nil
+}
+
+// SecretScan checks the repository for committed credentials with gitleaks,
+// returning the (redacted) findings as an error. Without .git in source only
+// the current files are scanned, so history-only leaks are missed
+func (m *JustMcp) SecretScan(ctx context.Context, source *dagger.Directory) (string, error) {
+	entries, err := source.Entries(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"gitleaks", "detect", "--source", "/src", "--redact", "--exit-code", "1"}
+	if !slices.Contains(entries, ".git") {
+		fmt.Println("⚠️  No .git in source, scanning files without history")
+		args = append(args, "--no-git")
+	}
+
+	container := dag.Container().
+		From("ghcr.io/gitleaks/gitleaks:v8.18.4").
+		WithDirectory("/src", source).
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "*"})
+
+	fmt.Println("🔐 Scanning for secrets...")
+	passed, out := execCapture(ctx, container, args)
+	if !passed {
+		return "", fmt.Errorf("secrets found:\n%s", out)
+	}
+	return "✅ No secrets found\n" + out, nil
+}
+
+// rustVersionField matches the rust-version field of Cargo.toml
+var rustVersionField = regexp.MustCompile(`(?m)^rust-version\s*=\s*"([^"]+)"`)
+
+// advertisedMsrv matches MSRV claims in a README: shields.io badges such as
+// badge/rust-1.88+ or badge/msrv-1.88.0, and text such as "MSRV: 1.88"
+var advertisedMsrv = regexp.MustCompile(`(?i)(?:badge/(?:msrv|rustc?)-|msrv[:\s]+v?)(\d+\.\d+(?:\.\d+)?)`)
+
+// MsrvBadgeCheck fails when the MSRV advertised in the README differs from
+// Cargo.toml's rust-version. 1.88 and 1.88.0 are treated as the same version
+func (m *JustMcp) MsrvBadgeCheck(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Path of the README within source
+	// +optional
+	// +default="README.md"
+	readme string,
+) (string, error) {
+	if readme == "" {
+		readme = "README.md"
+	}
+
+	manifest, err := source.File(path.Join(m.Subdir, "Cargo.toml")).Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Cargo.toml: %w", err)
+	}
+	match := rustVersionField.FindStringSubmatch(manifest)
+	if match == nil {
+		return "", fmt.Errorf("no rust-version in Cargo.toml")
+	}
+	msrv := match[1]
+
+	contents, err := source.File(readme).Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", readme, err)
+	}
+	claims := advertisedMsrv.FindAllStringSubmatch(contents, -1)
+	if len(claims) == 0 {
+		return fmt.Sprintf("⚠️  %s doesn't advertise an MSRV (Cargo.toml: %s)", readme, msrv), nil
+	}
+
+	var mismatches []string
+	for _, claim := range claims {
+		if normalizeMsrv(claim[1]) != normalizeMsrv(msrv) {
+			mismatches = append(mismatches, claim[0])
+		}
+	}
+	if len(mismatches) > 0 {
+		return "", fmt.Errorf("%s advertises a different MSRV than Cargo.toml's rust-version %s:\n%s", readme, msrv, strings.Join(mismatches, "\n"))
+	}
+	return fmt.Sprintf("✅ %s advertises MSRV %s, matching Cargo.toml", readme, msrv), nil
+}
+
+// normalizeMsrv drops a zero patch version, so 1.88.0 compares equal to 1.88
+func normalizeMsrv(version string) string {
+	if parts := strings.Split(version, "."); len(parts) == 3 && parts[2] == "0" {
+		return parts[0] + "." + parts[1]
+	}
+	return version
+}
+
+// DocLinkCheck builds the rustdoc HTML and checks its external http(s) links
+// with lychee, reporting dead ones
+func (m *JustMcp) DocLinkCheck(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Fail when dead links are found instead of just reporting them
+	// +optional
+	fail bool,
+) (string, error) {
+	docs := m.buildContainer(source).
+		WithExec([]string{"cargo", "doc", "--no-deps"}).
+		Directory(m.workdir() + "/target/doc")
+
+	container := dag.Container().
+		From("lycheeverse/lychee:0.15.1").
+		WithDirectory("/doc", docs)
+
+	fmt.Println("🔎 Checking documentation links...")
+	passed, out := execCapture(ctx, container, []string{
+		"lychee", "--no-progress",
+		"--scheme", "https", "--scheme", "http",
+		"/doc/just_mcp/**/*.html",
+	})
+	if passed {
+		return "✅ No dead links in documentation\n" + out, nil
+	}
+	if fail {
+		return "", fmt.Errorf("dead links found in documentation:\n%s", out)
+	}
+	return "⚠️  Dead links found in documentation:\n" + out, nil
+}
+
+// SizeGate builds the release binary for the platform and fails when it is
+// larger than the platform's budget in limitsFile, a JSON object mapping
+// target triples to maximum sizes in bytes, e.g.
+// {"x86_64-unknown-linux-gnu": 15000000}
+func (m *JustMcp) SizeGate(
+	ctx context.Context,
+	source *dagger.Directory,
+	// +optional
+	// +default="linux/amd64"
+	platform string,
+	// JSON object of target triple to maximum binary size in bytes
+	limitsFile *dagger.File,
+) (string, error) {
+	contents, err := limitsFile.Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read limits file: %w", err)
+	}
+	var limits map[string]int64
+	if err := json.Unmarshal([]byte(contents), &limits); err != nil {
+		return "", fmt.Errorf("invalid limits file: %w", err)
+	}
+	target := platformToTarget(platform)
+	limit, ok := limits[target]
+	if !ok {
+		return "", fmt.Errorf("limits file has no entry for %s", target)
+	}
+
+	fmt.Printf("📦 Building release binary for %s...\n", target)
+	size, err := m.buildBinary(source, platform, buildOptions{release: true}).Size(ctx)
+	if err != nil {
+		return "", fmt.Errorf("release build failed: %w", err)
+	}
+
+	report := fmt.Sprintf("📊 %s binary is %d bytes (limit %d bytes, %.1f%%)",
+		target, size, limit, float64(size)*100/float64(limit))
+	if int64(size) > limit {
+		return "", fmt.Errorf("binary exceeds size budget by %d bytes\n%s", int64(size)-limit, report)
+	}
+	return report, nil
+}
@@ -0,0 +1,713 @@
+package main
+
+import (
+	"context"
+	"dagger/just-mcp/internal/dagger"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Completions generates bash, zsh and fish completion scripts from the built binary
+// The just-mcp CLI doesn't expose a `completions` subcommand yet; until it does
+// this is a no-op returning an empty directory
+func (m *JustMcp) Completions(ctx context.Context, source *dagger.Directory) (*dagger.Directory, error) {
+	// Completions are platform independent, so generate them from a native build
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+
+	container := dag.Container().
+		From("rust:1.88.0").
+		WithFile("/usr/local/bin/just-mcp", binary)
+
+	// Probe the subcommand directly; clap exits non-zero on unknown subcommands
+	if supported, _ := execCapture(ctx, container, []string{"just-mcp", "completions", "bash"}); !supported {
+		fmt.Println("⚠️  just-mcp has no completions subcommand, skipping completions")
+		return dag.Directory(), nil
+	}
+
+	scripts := []struct {
+		shell string
+		file  string
+	}{
+		{"bash", "just-mcp.bash"},
+		{"zsh", "_just-mcp"},
+		{"fish", "just-mcp.fish"},
+	}
+
+	container = container.WithExec([]string{"mkdir", "-p", "/completions"})
+	for _, s := range scripts {
+		container = container.WithExec([]string{"just-mcp", "completions", s.shell}, dagger.ContainerWithExecOpts{
+			RedirectStdout: "/completions/" + s.file,
+		})
+	}
+
+	return container.Directory("/completions"), nil
+}
+
+// CompletionsCheck regenerates the completion scripts and fails if they differ
+// from the ones committed under completions/. In update mode the regenerated
+// directory is returned instead, ready to be exported over the committed one
+func (m *JustMcp) CompletionsCheck(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Return the regenerated completions instead of failing on drift
+	// +optional
+	update bool,
+) (*dagger.Directory, error) {
+	generated, err := m.Completions(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if update {
+		return generated, nil
+	}
+
+	entries, err := source.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	committed := dag.Directory()
+	if slices.Contains(entries, "completions") {
+		committed = source.Directory("completions")
+	}
+
+	passed, diff := execCapture(ctx,
+		dag.Container().
+			From(defaultArchiveImage).
+			WithDirectory("/committed", committed).
+			WithDirectory("/generated", generated),
+		[]string{"diff", "-ru", "/committed", "/generated"})
+	if !passed {
+		return nil, fmt.Errorf("committed completions are out of date, rerun with --update:\n%s", diff)
+	}
+
+	fmt.Println("✅ Committed completions are up to date")
+	return generated, nil
+}
+
+// ManPage renders a just-mcp.1 man page from the binary's --help and --version output
+// The CLI has no clap_mangen subcommand, so help2man is used to render it
+func (m *JustMcp) ManPage(ctx context.Context, source *dagger.Directory) (*dagger.File, error) {
+	binary := m.buildBinary(source, "linux/amd64", buildOptions{release: true})
+
+	return dag.Container().
+		From("rust:1.88.0").
+		WithExec([]string{"apt-get", "update"}).
+		WithExec([]string{"apt-get", "install", "-y", "help2man"}).
+		WithFile("/usr/local/bin/just-mcp", binary).
+		WithExec([]string{
+			"help2man",
+			"--no-info",
+			"--section", "1",
+			"--name", "Model Context Protocol server for justfile integration",
+			"--output", "/just-mcp.1",
+			"just-mcp",
+		}).
+		File("/just-mcp.1"), nil
+}
+
+// releaseVersion matches a semver version with an optional v prefix
+var releaseVersion = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// PreflightRelease performs cheap sanity checks on release parameters so bad
+// input fails fast instead of after a full ReleaseZigbuild: the version must
+// be semver (optionally v-prefixed), every target must be one ReleaseZigbuild
+// knows, and README.md and LICENSE must exist in source
+func (m *JustMcp) PreflightRelease(
+	ctx context.Context,
+	source *dagger.Directory,
+	version string,
+	// Targets to validate; defaults to every ReleaseZigbuild target
+	// +optional
+	targets []string,
+) (string, error) {
+	var problems []string
+	if !releaseVersion.MatchString(version) {
+		problems = append(problems, fmt.Sprintf("version %q is not a semver version (e.g. v1.2.3)", version))
+	}
+
+	if len(targets) == 0 {
+		targets = zigbuildTargets
+	}
+	for _, target := range targets {
+		if !slices.Contains(zigbuildTargets, target) {
+			problems = append(problems, fmt.Sprintf("unknown target %q", target))
+		}
+	}
+
+	entries, err := source.Entries(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list source: %w", err)
+	}
+	for _, name := range []string{"README.md", "LICENSE"} {
+		if !slices.Contains(entries, name) {
+			problems = append(problems, fmt.Sprintf("%s not found in source", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return "", fmt.Errorf("release preflight failed:\n%s", strings.Join(problems, "\n"))
+	}
+	return fmt.Sprintf("✅ Release %s is ready to build for %d target(s)", version, len(targets)), nil
+}
+
+// FullRelease runs ReleaseZigbuild and then generates checksums, cosign
+// signatures and an SBOM, returning all artifacts in a single directory
+func (m *JustMcp) FullRelease(
+	ctx context.Context,
+	source *dagger.Directory,
+	// +optional
+	// +default="v0.1.0"
+	version string,
+	// Cosign private key used to sign the archives (signing is skipped if not set)
+	// +optional
+	cosignKey *dagger.Secret,
+	// Password for the cosign private key
+	// +optional
+	cosignPassword *dagger.Secret,
+	// +optional
+	skipChecksums bool,
+	// +optional
+	skipSign bool,
+	// +optional
+	skipSbom bool,
+	// JSON build config with per-target features, strip and profile
+	// +optional
+	buildConfig *dagger.File,
+) (*dagger.Directory, error) {
+	releaseDir, err := m.ReleaseZigbuild(ctx, source, version, nil, buildConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	archives, err := releaseDir.Entries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list release archives: %w", err)
+	}
+
+	if !skipChecksums {
+		fmt.Println("🔐 Generating checksums...")
+		releaseDir = releaseDir.WithFile("SHA256SUMS", checksumFile(releaseDir, archives))
+	}
+
+	if !skipSign {
+		if cosignKey == nil {
+			fmt.Println("⚠️  No cosign key provided, skipping signatures")
+		} else {
+			fmt.Println("✍️  Signing release archives...")
+			releaseDir = releaseDir.WithDirectory(".", signBlobs(releaseDir, archives, cosignKey, cosignPassword))
+		}
+	}
+
+	if !skipSbom {
+		fmt.Println("📋 Generating SBOM...")
+		releaseDir = releaseDir.WithFile(fmt.Sprintf("just-mcp-%s.cdx.json", version), m.sbom(source))
+	}
+
+	return releaseDir, nil
+}
+
+// SignedManifest adds a SHA256SUMS manifest covering every file in artifacts
+// and a single cosign SHA256SUMS.sig over it, so one signature verification
+// vouches for all artifacts
+func (m *JustMcp) SignedManifest(
+	ctx context.Context,
+	artifacts *dagger.Directory,
+	// Cosign private key used to sign the manifest
+	cosignKey *dagger.Secret,
+	// Password for the cosign private key
+	// +optional
+	cosignPassword *dagger.Secret,
+) (*dagger.Directory, error) {
+	names, err := artifacts.Entries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no artifacts to sign")
+	}
+
+	fmt.Println("🔐 Generating checksums...")
+	manifest := artifacts.WithFile("SHA256SUMS", checksumFile(artifacts, names))
+
+	fmt.Println("✍️  Signing checksum manifest...")
+	return manifest.WithDirectory(".", signBlobs(manifest, []string{"SHA256SUMS"}, cosignKey, cosignPassword)), nil
+}
+
+// slsaDigest maps a digest algorithm to its hex value
+type slsaDigest map[string]string
+
+// slsaResource is a named, digested artifact or dependency in a statement
+type slsaResource struct {
+	Name   string     `json:"name,omitempty"`
+	URI    string     `json:"uri,omitempty"`
+	Digest slsaDigest `json:"digest"`
+}
+
+// provenanceStatement is an in-toto v1 statement carrying a SLSA v1 provenance
+// predicate
+type provenanceStatement struct {
+	Type          string         `json:"_type"`
+	Subject       []slsaResource `json:"subject"`
+	PredicateType string         `json:"predicateType"`
+	Predicate     struct {
+		BuildDefinition struct {
+			BuildType            string            `json:"buildType"`
+			ExternalParameters   map[string]string `json:"externalParameters"`
+			ResolvedDependencies []slsaResource    `json:"resolvedDependencies,omitempty"`
+		} `json:"buildDefinition"`
+		RunDetails struct {
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+		} `json:"runDetails"`
+	} `json:"predicate"`
+}
+
+// Provenance generates a SLSA v1 provenance statement (in-toto format) whose
+// subjects are every file in artifacts, returned as provenance.intoto.json
+// When a cosign key is given each artifact is also attested with
+// `cosign attest-blob`, producing a signed <artifact>.intoto.jsonl alongside it
+func (m *JustMcp) Provenance(
+	ctx context.Context,
+	artifacts *dagger.Directory,
+	// URI identifying the build platform, e.g. the CI workflow URL
+	builderID string,
+	// Source repository URI, e.g. git+https://github.com/toolprint/just-mcp
+	// +optional
+	sourceUri string,
+	// Git commit the artifacts were built from
+	// +optional
+	sourceDigest string,
+	// Release version passed to the build
+	// +optional
+	version string,
+	// Cosign private key used to sign the statement
+	// +optional
+	cosignKey *dagger.Secret,
+	// Password for the cosign private key
+	// +optional
+	cosignPassword *dagger.Secret,
+) (*dagger.Directory, error) {
+	names, err := artifacts.Entries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no artifacts to attest")
+	}
+
+	sums, err := checksumFile(artifacts, names).Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash artifacts: %w", err)
+	}
+
+	var statement provenanceStatement
+	statement.Type = "https://in-toto.io/Statement/v1"
+	statement.PredicateType = "https://slsa.dev/provenance/v1"
+	for _, line := range strings.Split(strings.TrimSpace(sums), "\n") {
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok {
+			return nil, fmt.Errorf("unexpected sha256sum output: %q", line)
+		}
+		statement.Subject = append(statement.Subject, slsaResource{Name: name, Digest: slsaDigest{"sha256": sum}})
+	}
+
+	build := &statement.Predicate.BuildDefinition
+	build.BuildType = "https://github.com/toolprint/just-mcp/.dagger@v1"
+	build.ExternalParameters = map[string]string{"version": version, "source": sourceUri}
+	if sourceUri != "" && sourceDigest != "" {
+		build.ResolvedDependencies = append(build.ResolvedDependencies, slsaResource{URI: sourceUri, Digest: slsaDigest{"gitCommit": sourceDigest}})
+	}
+	statement.Predicate.RunDetails.Builder.ID = builderID
+
+	data, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	out := dag.Directory().WithNewFile("provenance.intoto.json", string(data)+"\n")
+
+	if cosignKey != nil {
+		predicate, err := json.MarshalIndent(statement.Predicate, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println("✍️  Attesting artifacts...")
+		out = out.WithDirectory(".", attestBlobs(artifacts, names, string(predicate), cosignKey, cosignPassword))
+	}
+	return out, nil
+}
+
+// attestBlobs attests each named file in dir with the SLSA v1 provenance
+// predicate using `cosign attest-blob`, returning a directory of
+// <name>.intoto.jsonl signed attestations
+func attestBlobs(dir *dagger.Directory, names []string, predicate string, key *dagger.Secret, password *dagger.Secret) *dagger.Directory {
+	container := dag.Container().
+		From(defaultArchiveImage).
+		WithExec([]string{"apk", "add", "--no-cache", "cosign"}).
+		WithDirectory("/release", dir).
+		WithNewFile("/predicate.json", predicate).
+		WithMountedSecret("/cosign.key", key).
+		WithExec([]string{"mkdir", "-p", "/attestations"})
+	if password != nil {
+		container = container.WithSecretVariable("COSIGN_PASSWORD", password)
+	} else {
+		container = container.WithEnvVariable("COSIGN_PASSWORD", "")
+	}
+
+	for _, name := range names {
+		container = container.WithExec([]string{
+			"cosign", "attest-blob",
+			"--yes",
+			"--key", "/cosign.key",
+			"--predicate", "/predicate.json",
+			"--type", "slsaprovenance1",
+			"--output-attestation", fmt.Sprintf("/attestations/%s.intoto.jsonl", name),
+			"/release/" + name,
+		})
+	}
+
+	return container.Directory("/attestations")
+}
+
+// archiveLayout lists the entries every Package archive must contain
+var archiveLayout = []string{"just-mcp", "README.md", "LICENSE"}
+
+// VerifyArchive extracts a Package tarball in a fresh container and checks it
+// contains the expected entries with an executable just-mcp at the top level.
+// All discrepancies are reported together
+func (m *JustMcp) VerifyArchive(ctx context.Context, archive *dagger.File) (string, error) {
+	fmt.Println("📦 Extracting archive...")
+	extracted, err := dag.Container().
+		From(defaultArchiveImage).
+		WithFile("/archive.tar.gz", archive).
+		WithExec([]string{"mkdir", "/out"}).
+		WithExec([]string{"tar", "xzf", "/archive.tar.gz", "-C", "/out"}).
+		WithWorkdir("/out").
+		Sync(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract archive: %w", err)
+	}
+
+	entries, err := extracted.Directory("/out").Entries(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list archive entries: %w", err)
+	}
+
+	var problems []string
+	for _, name := range archiveLayout {
+		if !slices.Contains(entries, name) {
+			problems = append(problems, fmt.Sprintf("missing %s", name))
+		}
+	}
+	if slices.Contains(entries, "just-mcp") {
+		if passed, _ := execCapture(ctx, extracted, []string{"test", "-x", "just-mcp"}); !passed {
+			problems = append(problems, "just-mcp is not executable")
+		}
+	}
+
+	if len(problems) > 0 {
+		return "", fmt.Errorf("❌ archive layout is wrong:\n%s\n\nentries: %s",
+			strings.Join(problems, "\n"), strings.Join(entries, ", "))
+	}
+	return fmt.Sprintf("✅ Archive contains %s", strings.Join(entries, ", ")), nil
+}
+
+// changelogHeading matches a keep-a-changelog version heading such as
+// "## [1.2.3] - 2024-01-01" or "## [Unreleased]", capturing the version
+var changelogHeading = regexp.MustCompile(`^## \[?([^\]\s]+)\]?`)
+
+// changelogLinkRef matches the link reference definitions at the end of a
+// keep-a-changelog file, e.g. "[1.2.3]: https://..."
+var changelogLinkRef = regexp.MustCompile(`^\[[^\]]+\]:\s`)
+
+// ExtractReleaseNotes returns the body of one version's section from a
+// keep-a-changelog file, for use as release notes. A leading v in version is
+// ignored; "unreleased" selects the Unreleased section. Fails when the version
+// has no section or its section is empty
+func (m *JustMcp) ExtractReleaseNotes(ctx context.Context, changelog *dagger.File, version string) (*dagger.File, error) {
+	contents, err := changelog.Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changelog: %w", err)
+	}
+
+	wanted := strings.TrimPrefix(version, "v")
+	var versions, notes []string
+	found, inSection := false, false
+	for _, line := range strings.Split(contents, "\n") {
+		if heading := changelogHeading.FindStringSubmatch(line); heading != nil {
+			versions = append(versions, heading[1])
+			inSection = strings.EqualFold(strings.TrimPrefix(heading[1], "v"), wanted)
+			found = found || inSection
+			continue
+		}
+		if changelogLinkRef.MatchString(line) {
+			inSection = false
+		}
+		if inSection {
+			notes = append(notes, line)
+		}
+	}
+
+	if !found {
+		if strings.EqualFold(wanted, "unreleased") {
+			return nil, fmt.Errorf("changelog has no Unreleased section")
+		}
+		return nil, fmt.Errorf("changelog has no section for %s (found: %s)", version, strings.Join(versions, ", "))
+	}
+	body := strings.TrimSpace(strings.Join(notes, "\n"))
+	if body == "" {
+		return nil, fmt.Errorf("changelog section for %s is empty", version)
+	}
+
+	return dag.Directory().
+		WithNewFile("RELEASE_NOTES.md", body+"\n").
+		File("RELEASE_NOTES.md"), nil
+}
+
+// checksumFile computes a SHA256SUMS file for the named files in dir
+func checksumFile(dir *dagger.Directory, names []string) *dagger.File {
+	return dag.Container().
+		From("alpine:latest").
+		WithDirectory("/release", dir).
+		WithWorkdir("/release").
+		WithExec(append([]string{"sha256sum"}, names...), dagger.ContainerWithExecOpts{
+			RedirectStdout: "/SHA256SUMS",
+		}).
+		File("/SHA256SUMS")
+}
+
+// signBlobs signs each named file in dir with cosign, returning a directory
+// containing only the <name>.sig signatures
+func signBlobs(dir *dagger.Directory, names []string, key *dagger.Secret, password *dagger.Secret) *dagger.Directory {
+	container := dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "cosign"}).
+		WithDirectory("/release", dir).
+		WithMountedSecret("/cosign.key", key).
+		WithExec([]string{"mkdir", "-p", "/signatures"})
+	if password != nil {
+		container = container.WithSecretVariable("COSIGN_PASSWORD", password)
+	} else {
+		container = container.WithEnvVariable("COSIGN_PASSWORD", "")
+	}
+
+	for _, name := range names {
+		container = container.WithExec([]string{
+			"cosign", "sign-blob",
+			"--yes",
+			"--key", "/cosign.key",
+			"--output-signature", fmt.Sprintf("/signatures/%s.sig", name),
+			"/release/" + name,
+		})
+	}
+
+	return container.Directory("/signatures")
+}
+
+// sbom generates a CycloneDX SBOM for the crate
+func (m *JustMcp) sbom(source *dagger.Directory) *dagger.File {
+	return m.buildContainer(source).
+		WithExec([]string{"cargo", "install", "cargo-cyclonedx", "--locked"}).
+		WithExec([]string{"cargo", "cyclonedx", "--format", "json"}).
+		File(m.workdir() + "/just-mcp.cdx.json")
+}
+
+// InstallScriptTest runs the repository's install.sh in a clean container
+// against a locally built release archive and checks just-mcp ends up on PATH
+// The script is invoked as `install.sh --archive <path> --to /usr/local/bin`,
+// so the download path (latest release by default) isn't exercised
+func (m *JustMcp) InstallScriptTest(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Path of the install script within source
+	// +optional
+	// +default="install.sh"
+	script string,
+	// Runtime image to install into; the release binary links against glibc
+	// +optional
+	// +default="ubuntu:24.04"
+	image string,
+) (string, error) {
+	// Listing fails if the parent directory itself is missing
+	entries, err := source.Entries(ctx, dagger.DirectoryEntriesOpts{Path: path.Dir(script)})
+	if err != nil || !slices.Contains(entries, path.Base(script)) {
+		return "", fmt.Errorf("%s not found in source, nothing to test", script)
+	}
+
+	archive, err := m.Package(ctx, source, "linux/amd64", "v0.0.0-test", nil, false, false, nil, false, "", false, false, "", 0)
+	if err != nil {
+		return "", err
+	}
+
+	return dag.Container().
+		From(image).
+		WithFile("/tmp/install.sh", source.File(script)).
+		WithFile("/tmp/just-mcp.tar.gz", archive).
+		WithExec([]string{"sh", "/tmp/install.sh", "--archive", "/tmp/just-mcp.tar.gz", "--to", "/usr/local/bin"}).
+		WithExec([]string{"sh", "-c", "command -v just-mcp && just-mcp --version"}).
+		Stdout(ctx)
+}
+
+// cargoLicenseEntry is a single dependency from `cargo license --json`
+type cargoLicenseEntry struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	License    string `json:"license"`
+	Repository string `json:"repository"`
+}
+
+// thirdPartyLicensesTemplate renders the HTML variant of LicenseReport
+var thirdPartyLicensesTemplate = template.Must(template.New("licenses").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>just-mcp third-party licenses</title></head>
+<body>
+<h1>Third-party licenses</h1>
+<table>
+<tr><th>Crate</th><th>Version</th><th>License</th><th>Repository</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td>{{.Version}}</td><td>{{.License}}</td><td>{{.Repository}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// spdxValidateScript validates the SPDX expression in $LICENSE against the
+// SPDX license list, printing the normalized expression
+const spdxValidateScript = `import os, sys
+from license_expression import get_spdx_licensing
+info = get_spdx_licensing().validate(os.environ["LICENSE"], strict=True)
+if info.errors:
+    sys.exit("\n".join(info.errors))
+print(info.normalized_expression)
+`
+
+// BinaryLicense emits an SPDX 2.3 tag-value document declaring the license of
+// the just-mcp binary, taken from the license field of Cargo.toml. Fails when
+// the field is missing or isn't a valid SPDX expression
+func (m *JustMcp) BinaryLicense(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Creation time recorded in the document, as Unix seconds (SOURCE_DATE_EPOCH)
+	// Defaults to the epoch itself so the document is reproducible
+	// +optional
+	sourceDateEpoch int,
+) (*dagger.File, error) {
+	out, err := m.buildContainer(source).
+		WithExec([]string{"cargo", "metadata", "--no-deps", "--format-version", "1"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cargo metadata failed: %w", err)
+	}
+	var metadata cargoMetadata
+	if err := json.Unmarshal([]byte(out), &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse cargo metadata: %w", err)
+	}
+	index := -1
+	for i, pkg := range metadata.Packages {
+		if pkg.ManifestPath == m.workdir()+"/Cargo.toml" {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("no package manifest at the crate root")
+	}
+	pkg := metadata.Packages[index]
+	if pkg.License == "" {
+		return nil, fmt.Errorf("no license field in Cargo.toml")
+	}
+
+	fmt.Printf("🔍 Validating SPDX expression %q...\n", pkg.License)
+	passed, validated := execCapture(ctx,
+		dag.Container().
+			From("python:3.12-alpine").
+			WithExec([]string{"pip", "install", "--no-cache-dir", "license-expression"}).
+			WithEnvVariable("LICENSE", pkg.License),
+		[]string{"python3", "-c", spdxValidateScript})
+	if !passed {
+		return nil, fmt.Errorf("license %q is not a valid SPDX expression:\n%s", pkg.License, strings.TrimSpace(validated))
+	}
+	license := strings.TrimSpace(validated)
+
+	download := pkg.Repository
+	if download == "" {
+		download = "NOASSERTION"
+	}
+	document := strings.Join([]string{
+		"SPDXVersion: SPDX-2.3",
+		"DataLicense: CC0-1.0",
+		"SPDXID: SPDXRef-DOCUMENT",
+		fmt.Sprintf("DocumentName: %s-%s", pkg.Name, pkg.Version),
+		fmt.Sprintf("DocumentNamespace: https://spdx.org/spdxdocs/%s-%s", pkg.Name, pkg.Version),
+		"Creator: Tool: just-mcp-dagger",
+		"Created: " + time.Unix(int64(sourceDateEpoch), 0).UTC().Format(time.RFC3339),
+		"",
+		"PackageName: " + pkg.Name,
+		"SPDXID: SPDXRef-Package-" + pkg.Name,
+		"PackageVersion: " + pkg.Version,
+		"PackageDownloadLocation: " + download,
+		"FilesAnalyzed: false",
+		"PackageLicenseConcluded: " + license,
+		"PackageLicenseDeclared: " + license,
+		"PackageCopyrightText: NOASSERTION",
+		"",
+		"Relationship: SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-" + pkg.Name,
+	}, "\n") + "\n"
+
+	return dag.Directory().
+		WithNewFile("just-mcp.spdx", document).
+		File("just-mcp.spdx"), nil
+}
+
+// LicenseReport generates a summary of the licenses of all dependencies using
+// cargo-license, as plain text or HTML
+func (m *JustMcp) LicenseReport(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Output format: "text" or "html"
+	// +optional
+	// +default="text"
+	format string,
+) (*dagger.File, error) {
+	container := m.buildContainer(source).
+		WithExec([]string{"cargo", "install", "cargo-license", "--locked"})
+
+	switch format {
+	case "", "text":
+		return container.
+			WithExec([]string{"cargo", "license", "--avoid-dev-deps"}, dagger.ContainerWithExecOpts{
+				RedirectStdout: "/THIRD-PARTY-LICENSES.txt",
+			}).
+			File("/THIRD-PARTY-LICENSES.txt"), nil
+
+	case "html":
+		out, err := container.
+			WithExec([]string{"cargo", "license", "--avoid-dev-deps", "--json"}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var deps []cargoLicenseEntry
+		if err := json.Unmarshal([]byte(out), &deps); err != nil {
+			return nil, fmt.Errorf("failed to parse cargo-license output: %w", err)
+		}
+		var html strings.Builder
+		if err := thirdPartyLicensesTemplate.Execute(&html, deps); err != nil {
+			return nil, err
+		}
+
+		return dag.Directory().
+			WithNewFile("THIRD-PARTY-LICENSES.html", html.String()).
+			File("THIRD-PARTY-LICENSES.html"), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported license report format %q (expected text or html)", format)
+	}
+}
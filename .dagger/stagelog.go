@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"dagger/just-mcp/internal/dagger"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// stageLog describes a recorded stage and the log file holding its output
+type stageLog struct {
+	name   string
+	file   string
+	passed bool
+}
+
+// stageLogs collects the full output of each pipeline stage as log files
+// A nil *stageLogs discards everything, so callers needn't check for it
+type stageLogs struct {
+	mu     sync.Mutex
+	dir    *dagger.Directory
+	stages []stageLog
+}
+
+// newStageLogs creates an empty stage log collection
+func newStageLogs() *stageLogs {
+	return &stageLogs{dir: dag.Directory()}
+}
+
+// record writes a stage's output, followed by its error if it failed, to a
+// log file numbered in execution order (e.g. "03-clippy.log")
+func (l *stageLogs) record(name, output string, err error) {
+	if l == nil {
+		return
+	}
+	if err != nil {
+		output += "\n" + err.Error() + "\n"
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file := fmt.Sprintf("%02d-%s.log", len(l.stages)+1, strings.NewReplacer(" ", "-", "/", "-").Replace(name))
+	l.dir = l.dir.WithNewFile(file, output)
+	l.stages = append(l.stages, stageLog{name: name, file: file, passed: err == nil})
+}
+
+// recorded returns the stages recorded so far, in execution order
+func (l *stageLogs) recorded() []stageLog {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.stages)
+}
+
+// directory returns the log files recorded so far
+func (l *stageLogs) directory() *dagger.Directory {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.dir
+}
+
+// stageOutput runs a stage's container and returns its stdout followed by its
+// stderr, where cargo prints compiler diagnostics and test harness output
+// On failure the error already carries both streams
+func stageOutput(ctx context.Context, container *dagger.Container) (string, error) {
+	stdout, err := container.Stdout(ctx)
+	if err != nil {
+		return "", err
+	}
+	stderr, err := container.Stderr(ctx)
+	if err != nil {
+		return stdout, err
+	}
+	return stdout + stderr, nil
+}
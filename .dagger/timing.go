@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stageTiming is the recorded duration of a single pipeline stage
+type stageTiming struct {
+	name     string
+	duration time.Duration
+}
+
+// stageTimer records how long each pipeline stage takes
+type stageTimer struct {
+	mu     sync.Mutex
+	stages []stageTiming
+}
+
+// track starts timing a stage; call the returned function when it finishes
+func (t *stageTimer) track(name string) func() {
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start).Round(time.Millisecond)
+		fmt.Printf("⏱️  %s took %s\n", name, elapsed)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.stages = append(t.stages, stageTiming{name: name, duration: elapsed})
+	}
+}
+
+// durations returns the recorded duration of each stage by name
+func (t *stageTimer) durations() map[string]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	durations := make(map[string]time.Duration, len(t.stages))
+	for _, s := range t.stages {
+		durations[s.name] = s.duration
+	}
+	return durations
+}
+
+// summary renders the recorded stage durations as a table
+func (t *stageTimer) summary() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("⏱️  Stage durations:\n")
+	var total time.Duration
+	for _, s := range t.stages {
+		fmt.Fprintf(&b, "   %-24s %s\n", s.name, s.duration)
+		total += s.duration
+	}
+	fmt.Fprintf(&b, "   %-24s %s\n", "total", total)
+	return b.String()
+}
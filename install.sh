@@ -0,0 +1,67 @@
+#!/bin/sh
+# Install just-mcp from a GitHub release archive
+#
+# Usage: install.sh [--version vX.Y.Z] [--archive <path>] [--to <dir>]
+#   --version  Release to download (default: latest)
+#   --archive  Install from a local just-mcp-*.tar.gz instead of downloading
+#   --to       Directory to install into (default: ~/.local/bin)
+
+set -eu
+
+repo="toolprint/just-mcp"
+version=""
+archive=""
+dest="${HOME}/.local/bin"
+
+die() {
+    echo "install.sh: $*" >&2
+    exit 1
+}
+
+while [ $# -gt 0 ]; do
+    case "$1" in
+        --version) [ $# -ge 2 ] || die "--version needs a value"; version="$2"; shift 2 ;;
+        --archive) [ $# -ge 2 ] || die "--archive needs a value"; archive="$2"; shift 2 ;;
+        --to) [ $# -ge 2 ] || die "--to needs a value"; dest="$2"; shift 2 ;;
+        -h|--help) sed -n '2,7p' "$0" | sed 's/^# \{0,1\}//'; exit 0 ;;
+        *) die "unknown argument: $1" ;;
+    esac
+done
+
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+if [ -z "$archive" ]; then
+    command -v curl >/dev/null || die "curl is required to download a release"
+
+    case "$(uname -s)-$(uname -m)" in
+        Linux-x86_64) target="x86_64-unknown-linux-gnu" ;;
+        Linux-aarch64|Linux-arm64) target="aarch64-unknown-linux-gnu" ;;
+        Darwin-x86_64) target="x86_64-apple-darwin" ;;
+        Darwin-arm64) target="aarch64-apple-darwin" ;;
+        *) die "no release archive for $(uname -s) $(uname -m)" ;;
+    esac
+
+    if [ -z "$version" ]; then
+        # The latest release URL redirects to .../releases/tag/<version>
+        latest=$(curl -sSfL -o /dev/null -w '%{url_effective}' "https://github.com/${repo}/releases/latest")
+        version="${latest##*/}"
+    fi
+
+    archive="${tmp}/just-mcp-${version}-${target}.tar.gz"
+    echo "Downloading just-mcp ${version} for ${target}..."
+    curl -sSfL -o "$archive" \
+        "https://github.com/${repo}/releases/download/${version}/just-mcp-${version}-${target}.tar.gz" \
+        || die "failed to download just-mcp ${version} for ${target}"
+fi
+
+[ -f "$archive" ] || die "archive not found: $archive"
+
+tar -xzf "$archive" -C "$tmp"
+[ -f "${tmp}/just-mcp" ] || die "no just-mcp binary in $archive"
+
+mkdir -p "$dest"
+cp "${tmp}/just-mcp" "${dest}/just-mcp"
+chmod 755 "${dest}/just-mcp"
+
+echo "Installed just-mcp to ${dest}/just-mcp"